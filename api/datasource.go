@@ -125,3 +125,117 @@ func (c *Client) DeleteDatasource(id string) error {
 		}
 	}
 }
+
+type DatasourcePage struct {
+	Content       []Datasource `json:"content"`
+	Number        int          `json:"number"`
+	Size          int          `json:"size"`
+	TotalPages    int          `json:"totalPages"`
+	TotalElements int64        `json:"totalElements"`
+	Last          bool         `json:"last"`
+}
+
+func (c *Client) ListDatasources(opts ListOptions) (DatasourcePage, error) {
+	// logger := fwhelpers.GetLogger()
+
+	method := "GET"
+	url := c.Host + "/api/v1/catalog/meshdb/?" + opts.query()
+
+	b, statusCode, _, _, _, err := c.doRequest(method, url, nil, nil)
+	if err != nil {
+		return DatasourcePage{}, err
+	}
+
+	dsPage := DatasourcePage{}
+	if statusCode >= 200 && statusCode <= 299 {
+		err = json.Unmarshal(b, &dsPage)
+		return dsPage, err
+	} else {
+		msg, err := c.getAPIError(b)
+		if err != nil {
+			return dsPage, err
+		} else {
+			return dsPage, fmt.Errorf(msg)
+		}
+	}
+}
+
+// Iterates over all sources matching the list options,
+// fetching further pages as required.
+type DatasourceIterator struct {
+	client  *Client
+	opts    ListOptions
+	page    DatasourcePage
+	idx     int
+	fetched bool
+	err     error
+}
+
+func (c *Client) IterateDatasources(opts ListOptions) *DatasourceIterator {
+	if opts.Size <= 0 {
+		opts.Size = listPageSize
+	}
+	return &DatasourceIterator{client: c, opts: opts}
+}
+
+// Next advances to the next source, returning false once all
+// pages are consumed or a request fails.
+func (it *DatasourceIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if it.fetched && it.idx+1 < len(it.page.Content) {
+		it.idx++
+		return true
+	}
+	if it.fetched && (it.page.Last || it.opts.Page >= it.page.TotalPages) {
+		return false
+	}
+
+	it.page, it.err = it.client.ListDatasources(it.opts)
+	if it.err != nil {
+		return false
+	}
+	it.fetched = true
+	it.opts.Page++
+	it.idx = 0
+
+	return len(it.page.Content) > 0
+}
+
+func (it *DatasourceIterator) Datasource() Datasource {
+	return it.page.Content[it.idx]
+}
+
+func (it *DatasourceIterator) Err() error {
+	return it.err
+}
+
+func (c *Client) ListAllDatasources(opts ListOptions) ([]Datasource, error) {
+	items := []Datasource{}
+
+	it := c.IterateDatasources(opts)
+	for it.Next() {
+		items = append(items, it.Datasource())
+	}
+
+	return items, it.Err()
+}
+
+// FindDatasourceByShortName returns ErrNotFound if no source
+// has the exact short name.
+func (c *Client) FindDatasourceByShortName(shortName string) (Datasource, error) {
+	it := c.IterateDatasources(ListOptions{ShortName: shortName})
+	for it.Next() {
+		item := it.Datasource()
+		if item.ShortName == shortName && !item.Deleted {
+			return item, nil
+		}
+	}
+	if it.Err() != nil {
+		return Datasource{}, it.Err()
+	}
+
+	return Datasource{}, ErrNotFound
+}
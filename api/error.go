@@ -2,10 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 )
 
+// Returned by the lookup helpers when nothing matches.
+var ErrNotFound = errors.New("not found")
+
 type APIError struct {
 	Timestamp        string            `json:"timestamp"`
 	Status           int16             `json:"status"`
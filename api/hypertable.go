@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 type Hypertable struct {
@@ -17,6 +18,7 @@ type Hypertable struct {
 	SqlSelect              string                     `json:"sqlSelect,omitempty"`
 	CronTiming             string                     `json:"cronTiming,omitempty"`
 	CronTimingString       string                     `json:"cronTimingString,omitempty"`
+	TimeZone               string                     `json:"timeZone,omitempty"`
 	Stages                 []HypertableScheduledStage `json:"stages,omitempty"`
 	BackingTable           string                     `json:"backingTable,omitempty"`
 	BackingTableUpdateMode string                     `json:"backingTableUpdateMode,omitempty"`
@@ -24,19 +26,41 @@ type Hypertable struct {
 	PartitionKeys          []string                   `json:"partitionKeys,omitempty"`
 	RESTEndpoint           string                     `json:"restEndpoint,omitempty"`
 	Status                 bool                       `json:"status,omitempty"`
+	LastSuccessTime        string                     `json:"lastSuccessTime,omitempty"`
+	LastFailureTime        string                     `json:"lastFailureTime,omitempty"`
+	NextRunTime            string                     `json:"nextRunTime,omitempty"`
 	Deleted                bool                       `json:"deleted,omitempty"`
 }
 
 type HypertableScheduledStage struct {
-	ID          int64  `json:"id"`
-	Query       string `json:"query"`
-	Name        string `json:"name"`
-	ShortName   string `json:"shortName"`
-	Description string `json:"description,omitempty"`
-	RunStatus   string `json:"runStatus,omitempty"`
-	StartTime   string `json:"startTime,omitempty"`
-	Duration    string `json:"duration,omitempty"`
-	Errors      int64  `json:"errors,omitempty"`
+	ID          int64    `json:"id"`
+	Query       string   `json:"query"`
+	Name        string   `json:"name"`
+	ShortName   string   `json:"shortName"`
+	Description string   `json:"description,omitempty"`
+	DependsOn   []string `json:"dependsOn,omitempty"`
+	RunStatus   string   `json:"runStatus,omitempty"`
+	StartTime   string   `json:"startTime,omitempty"`
+	Duration    string   `json:"duration,omitempty"`
+	Errors      int64    `json:"errors,omitempty"`
+}
+
+// Stage run statuses reported by the server.
+const (
+	RunStatusPending   = "PENDING"
+	RunStatusRunning   = "RUNNING"
+	RunStatusSuccess   = "SUCCESS"
+	RunStatusFailed    = "FAILED"
+	RunStatusCancelled = "CANCELLED"
+)
+
+func (s HypertableScheduledStage) Succeeded() bool {
+	return strings.EqualFold(s.RunStatus, RunStatusSuccess)
+}
+
+func (s HypertableScheduledStage) Failed() bool {
+	return strings.EqualFold(s.RunStatus, RunStatusFailed) ||
+		strings.EqualFold(s.RunStatus, RunStatusCancelled)
 }
 
 func (c *Client) CreateHypertable(payload Hypertable) (Hypertable, error) {
@@ -71,6 +95,8 @@ func (c *Client) CreateHypertable(payload Hypertable) (Hypertable, error) {
 func (c *Client) UpdateStatusHypertable(id string, payload Hypertable, status bool) error {
 	// logger := fwhelpers.GetLogger()
 
+	defer c.cache.invalidateHypertable(id)
+
 	method := "GET"
 	query := fmt.Sprintf(
 		"catalogName=hypertables&schemaName=default&tableName=%s&id=%s&status=%t",
@@ -123,6 +149,8 @@ func (c *Client) ReadHypertable(id string) (Hypertable, error) {
 func (c *Client) UpdateHypertable(id string, payload Hypertable) (Hypertable, error) {
 	// logger := fwhelpers.GetLogger()
 
+	defer c.cache.invalidateHypertable(id)
+
 	method := "PUT"
 	url := c.Host + "/api/v1/catalog/hypertable/" + id
 	body, err := json.Marshal(payload)
@@ -152,6 +180,8 @@ func (c *Client) UpdateHypertable(id string, payload Hypertable) (Hypertable, er
 func (c *Client) DeleteHypertable(id string) error {
 	// logger := fwhelpers.GetLogger()
 
+	defer c.cache.invalidateHypertable(id)
+
 	method := "DELETE"
 	url := c.Host + "/api/v1/catalog/hypertable/" + id
 
@@ -171,3 +201,158 @@ func (c *Client) DeleteHypertable(id string) error {
 		}
 	}
 }
+
+type HypertablePage struct {
+	Content       []Hypertable `json:"content"`
+	Number        int          `json:"number"`
+	Size          int          `json:"size"`
+	TotalPages    int          `json:"totalPages"`
+	TotalElements int64        `json:"totalElements"`
+	Last          bool         `json:"last"`
+}
+
+func (c *Client) ListHypertables(opts ListOptions) (HypertablePage, error) {
+	// logger := fwhelpers.GetLogger()
+
+	method := "GET"
+	url := c.Host + "/api/v1/catalog/hypertable/?" + opts.query()
+
+	b, statusCode, _, _, _, err := c.doRequest(method, url, nil, nil)
+	if err != nil {
+		return HypertablePage{}, err
+	}
+
+	htPage := HypertablePage{}
+	if statusCode >= 200 && statusCode <= 299 {
+		err = json.Unmarshal(b, &htPage)
+		return htPage, err
+	} else {
+		msg, err := c.getAPIError(b)
+		if err != nil {
+			return htPage, err
+		} else {
+			return htPage, fmt.Errorf(msg)
+		}
+	}
+}
+
+// Iterates over all hypertables matching the list options,
+// fetching further pages as required.
+type HypertableIterator struct {
+	client  *Client
+	opts    ListOptions
+	page    HypertablePage
+	idx     int
+	fetched bool
+	err     error
+}
+
+func (c *Client) IterateHypertables(opts ListOptions) *HypertableIterator {
+	if opts.Size <= 0 {
+		opts.Size = listPageSize
+	}
+	return &HypertableIterator{client: c, opts: opts}
+}
+
+// Next advances to the next hypertable, returning false once all
+// pages are consumed or a request fails.
+func (it *HypertableIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if it.fetched && it.idx+1 < len(it.page.Content) {
+		it.idx++
+		return true
+	}
+	if it.fetched && (it.page.Last || it.opts.Page >= it.page.TotalPages) {
+		return false
+	}
+
+	it.page, it.err = it.client.ListHypertables(it.opts)
+	if it.err != nil {
+		return false
+	}
+	it.fetched = true
+	it.opts.Page++
+	it.idx = 0
+
+	return len(it.page.Content) > 0
+}
+
+func (it *HypertableIterator) Hypertable() Hypertable {
+	return it.page.Content[it.idx]
+}
+
+func (it *HypertableIterator) Err() error {
+	return it.err
+}
+
+func (c *Client) ListAllHypertables(opts ListOptions) ([]Hypertable, error) {
+	items := []Hypertable{}
+
+	it := c.IterateHypertables(opts)
+	for it.Next() {
+		items = append(items, it.Hypertable())
+	}
+
+	return items, it.Err()
+}
+
+// FindHypertableByShortName returns ErrNotFound if no hypertable
+// has the exact short name.
+func (c *Client) FindHypertableByShortName(shortName string) (Hypertable, error) {
+	it := c.IterateHypertables(ListOptions{ShortName: shortName})
+	for it.Next() {
+		item := it.Hypertable()
+		if item.ShortName == shortName && !item.Deleted {
+			return item, nil
+		}
+	}
+	if it.Err() != nil {
+		return Hypertable{}, it.Err()
+	}
+
+	return Hypertable{}, ErrNotFound
+}
+
+// Column of a hypertable as exposed to queries.
+type HypertableColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// ReadHypertableColumns is served from the read cache if possible.
+func (c *Client) ReadHypertableColumns(id string) ([]HypertableColumn, error) {
+	v, err := c.cache.get(cacheKindColumns, id, func() (interface{}, error) {
+		return c.readHypertableColumns(id)
+	})
+	columns, _ := v.([]HypertableColumn)
+	return columns, err
+}
+
+func (c *Client) readHypertableColumns(id string) ([]HypertableColumn, error) {
+	// logger := fwhelpers.GetLogger()
+
+	method := "GET"
+	url := c.Host + "/api/v1/catalog/hypertable/" + id + "/columns"
+
+	b, statusCode, _, _, _, err := c.doRequest(method, url, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []HypertableColumn{}
+	if statusCode >= 200 && statusCode <= 299 {
+		err = json.Unmarshal(b, &columns)
+		return columns, err
+	} else {
+		msg, err := c.getAPIError(b)
+		if err != nil {
+			return columns, err
+		} else {
+			return columns, fmt.Errorf(msg)
+		}
+	}
+}
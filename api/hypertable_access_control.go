@@ -3,7 +3,6 @@ package api
 import (
 	"encoding/json"
 	"fmt"
-	"strings"
 )
 
 type HypertableAccessControl struct {
@@ -24,25 +23,53 @@ type accessControlUserOrGroup struct {
 	Member   string `json:"member"`
 }
 
-func (c *Client) GetHypertableAccessControlStateId(hypertableId string, userOrGroup string) string {
-	if hypertableId != "" && userOrGroup != "" {
-		return fmt.Sprintf("%s:%s", hypertableId, userOrGroup)
-	} else {
-		return ""
+// Policies returns the access control entries flattened.
+func (l HypertableAccessControlList) Policies() []Policy {
+	policies := []Policy{}
+	for _, m := range l.Users {
+		policies = append(policies, Policy{
+			Kind: PolicyKindAccessControl, PolicyId: m.PolicyId, HypertableId: l.HypertableId,
+			MemberType: MemberTypeUser, Member: m.Member,
+		})
+	}
+	for _, m := range l.Groups {
+		policies = append(policies, Policy{
+			Kind: PolicyKindAccessControl, PolicyId: m.PolicyId, HypertableId: l.HypertableId,
+			MemberType: MemberTypeGroup, Member: m.Member,
+		})
 	}
+	return policies
 }
 
-func (c *Client) ParseHypertableAccessControlStateId(stateId string) []string {
-	if stateId != "" && strings.Contains(stateId, ":") {
-		return strings.Split(stateId, ":")
-	} else {
-		return []string{}
+// Members returns the users or groups with access, by member type.
+func (l HypertableAccessControlList) Members(memberType string) []string {
+	list := l.Users
+	if memberType == MemberTypeGroup {
+		list = l.Groups
 	}
+
+	members := []string{}
+	for _, m := range list {
+		members = append(members, m.Member)
+	}
+	return members
+}
+
+func (c *Client) GetHypertableAccessControlStateId(hypertableId string, memberType string, member string) string {
+	return formatPolicyStateId(PolicyStateId{
+		HypertableId: hypertableId, MemberType: memberType, Member: member,
+	}, false)
+}
+
+func (c *Client) ParseHypertableAccessControlStateId(stateId string) (PolicyStateId, error) {
+	return parsePolicyStateId(stateId, false)
 }
 
 func (c *Client) CreateHypertableAccessControl(payload HypertableAccessControl) (string, error) {
 	// logger := fwhelpers.GetLogger()
 
+	defer c.cache.invalidate(string(PolicyKindAccessControl), payload.HypertableId)
+
 	method := "POST"
 	url := c.Host + "/api/v1/access-control/access"
 	body, err := json.Marshal(payload)
@@ -67,7 +94,16 @@ func (c *Client) CreateHypertableAccessControl(payload HypertableAccessControl)
 	}
 }
 
+// ReadHypertableAccessControl is served from the read cache if possible.
 func (c *Client) ReadHypertableAccessControl(id string) (HypertableAccessControlList, error) {
+	v, err := c.cache.get(string(PolicyKindAccessControl), id, func() (interface{}, error) {
+		return c.readHypertableAccessControl(id)
+	})
+	list, _ := v.(HypertableAccessControlList)
+	return list, err
+}
+
+func (c *Client) readHypertableAccessControl(id string) (HypertableAccessControlList, error) {
 	// logger := fwhelpers.GetLogger()
 
 	method := "GET"
@@ -95,6 +131,8 @@ func (c *Client) ReadHypertableAccessControl(id string) (HypertableAccessControl
 func (c *Client) DeleteHypertableAccessControl(payload HypertableAccessControl) error {
 	// logger := fwhelpers.GetLogger()
 
+	defer c.cache.invalidate(string(PolicyKindAccessControl), payload.HypertableId)
+
 	method := "DELETE"
 	url := c.Host + "/api/v1/access-control/access"
 	body, err := json.Marshal(payload)
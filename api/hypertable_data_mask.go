@@ -3,7 +3,6 @@ package api
 import (
 	"encoding/json"
 	"fmt"
-	"strings"
 )
 
 type HypertableDataMask struct {
@@ -28,25 +27,42 @@ type dataMaskUserOrGroup struct {
 	Column        string `json:"column"`
 }
 
-func (c *Client) GetHypertableDataMaskStateId(hypertableId string, userOrGroup string, column string) string {
-	if hypertableId != "" && userOrGroup != "" && column != "" {
-		return fmt.Sprintf("%s:%s:%s", hypertableId, userOrGroup, column)
-	} else {
-		return ""
+// Policies returns the data mask entries flattened.
+func (m HypertableDataMasks) Policies() []Policy {
+	policies := []Policy{}
+	for _, u := range m.Users {
+		policies = append(policies, Policy{
+			Kind: PolicyKindDataMask, PolicyId: u.PolicyId, HypertableId: m.HypertableId,
+			MemberType: MemberTypeUser, Member: u.Member,
+			Column: u.Column, MaskingOption: u.MaskingOption,
+		})
+	}
+	for _, g := range m.Groups {
+		policies = append(policies, Policy{
+			Kind: PolicyKindDataMask, PolicyId: g.PolicyId, HypertableId: m.HypertableId,
+			MemberType: MemberTypeGroup, Member: g.Member,
+			Column: g.Column, MaskingOption: g.MaskingOption,
+		})
 	}
+	return policies
 }
 
-func (c *Client) ParseHypertableDataMaskStateId(stateId string) []string {
-	if stateId != "" && strings.Contains(stateId, ":") {
-		return strings.Split(stateId, ":")
-	} else {
-		return []string{}
-	}
+func (c *Client) GetHypertableDataMaskStateId(hypertableId string, memberType string, member string, column string) string {
+	return formatPolicyStateId(PolicyStateId{
+		HypertableId: hypertableId, MemberType: memberType,
+		Member: member, Column: column,
+	}, true)
+}
+
+func (c *Client) ParseHypertableDataMaskStateId(stateId string) (PolicyStateId, error) {
+	return parsePolicyStateId(stateId, true)
 }
 
 func (c *Client) CreateHypertableDataMask(payload HypertableDataMask) (string, error) {
 	// logger := fwhelpers.GetLogger()
 
+	defer c.cache.invalidate(string(PolicyKindDataMask), payload.HypertableId)
+
 	method := "POST"
 	url := c.Host + "/api/v1/access-control/mask"
 	body, err := json.Marshal(payload)
@@ -71,7 +87,16 @@ func (c *Client) CreateHypertableDataMask(payload HypertableDataMask) (string, e
 	}
 }
 
+// ReadHypertableDataMask is served from the read cache if possible.
 func (c *Client) ReadHypertableDataMask(id string) (HypertableDataMasks, error) {
+	v, err := c.cache.get(string(PolicyKindDataMask), id, func() (interface{}, error) {
+		return c.readHypertableDataMask(id)
+	})
+	list, _ := v.(HypertableDataMasks)
+	return list, err
+}
+
+func (c *Client) readHypertableDataMask(id string) (HypertableDataMasks, error) {
 	// logger := fwhelpers.GetLogger()
 
 	method := "GET"
@@ -99,6 +124,8 @@ func (c *Client) ReadHypertableDataMask(id string) (HypertableDataMasks, error)
 func (c *Client) DeleteHypertableDataMask(payload HypertableDataMask) error {
 	// logger := fwhelpers.GetLogger()
 
+	defer c.cache.invalidate(string(PolicyKindDataMask), payload.HypertableId)
+
 	method := "DELETE"
 	url := c.Host + "/api/v1/access-control/mask"
 	body, err := json.Marshal(payload)
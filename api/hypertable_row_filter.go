@@ -3,7 +3,6 @@ package api
 import (
 	"encoding/json"
 	"fmt"
-	"strings"
 )
 
 type HypertableRowFilter struct {
@@ -28,25 +27,42 @@ type rowFilterUserOrGroup struct {
 	Column           string `json:"column"`
 }
 
-func (c *Client) GetHypertableRowFilterStateId(hypertableId string, userOrGroup string, column string) string {
-	if hypertableId != "" && userOrGroup != "" && column != "" {
-		return fmt.Sprintf("%s:%s:%s", hypertableId, userOrGroup, column)
-	} else {
-		return ""
+// Policies returns the row filter entries flattened.
+func (f HypertableRowFilters) Policies() []Policy {
+	policies := []Policy{}
+	for _, u := range f.Users {
+		policies = append(policies, Policy{
+			Kind: PolicyKindRowFilter, PolicyId: u.PolicyId, HypertableId: f.HypertableId,
+			MemberType: MemberTypeUser, Member: u.Member,
+			Column: u.Column, FilterExpression: u.FilterExpression,
+		})
+	}
+	for _, g := range f.Groups {
+		policies = append(policies, Policy{
+			Kind: PolicyKindRowFilter, PolicyId: g.PolicyId, HypertableId: f.HypertableId,
+			MemberType: MemberTypeGroup, Member: g.Member,
+			Column: g.Column, FilterExpression: g.FilterExpression,
+		})
 	}
+	return policies
 }
 
-func (c *Client) ParseHypertableRowFilterStateId(stateId string) []string {
-	if stateId != "" && strings.Contains(stateId, ":") {
-		return strings.Split(stateId, ":")
-	} else {
-		return []string{}
-	}
+func (c *Client) GetHypertableRowFilterStateId(hypertableId string, memberType string, member string, column string) string {
+	return formatPolicyStateId(PolicyStateId{
+		HypertableId: hypertableId, MemberType: memberType,
+		Member: member, Column: column,
+	}, true)
+}
+
+func (c *Client) ParseHypertableRowFilterStateId(stateId string) (PolicyStateId, error) {
+	return parsePolicyStateId(stateId, true)
 }
 
 func (c *Client) CreateHypertableRowFilter(payload HypertableRowFilter) (string, error) {
 	// logger := fwhelpers.GetLogger()
 
+	defer c.cache.invalidate(string(PolicyKindRowFilter), payload.HypertableId)
+
 	method := "POST"
 	url := c.Host + "/api/v1/access-control/rowFilter"
 	body, err := json.Marshal(payload)
@@ -71,7 +87,16 @@ func (c *Client) CreateHypertableRowFilter(payload HypertableRowFilter) (string,
 	}
 }
 
+// ReadHypertableRowFilter is served from the read cache if possible.
 func (c *Client) ReadHypertableRowFilter(id string) (HypertableRowFilters, error) {
+	v, err := c.cache.get(string(PolicyKindRowFilter), id, func() (interface{}, error) {
+		return c.readHypertableRowFilter(id)
+	})
+	list, _ := v.(HypertableRowFilters)
+	return list, err
+}
+
+func (c *Client) readHypertableRowFilter(id string) (HypertableRowFilters, error) {
 	// logger := fwhelpers.GetLogger()
 
 	method := "GET"
@@ -99,6 +124,8 @@ func (c *Client) ReadHypertableRowFilter(id string) (HypertableRowFilters, error
 func (c *Client) DeleteHypertableRowFilter(payload HypertableRowFilter) error {
 	// logger := fwhelpers.GetLogger()
 
+	defer c.cache.invalidate(string(PolicyKindRowFilter), payload.HypertableId)
+
 	method := "DELETE"
 	url := c.Host + "/api/v1/access-control/rowFilter"
 	body, err := json.Marshal(payload)
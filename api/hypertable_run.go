@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Interval between stage status polls while waiting for a run.
+const runPollInterval = 5 * time.Second
+
+// Returned when a stage of a hypertable run fails.
+type HypertableRunError struct {
+	Stage  string
+	Status string
+	Errors int64
+}
+
+func (e *HypertableRunError) Error() string {
+	return fmt.Sprintf(
+		"stage %q of the hypertable run finished with status %s and %d errors",
+		e.Stage, e.Status, e.Errors,
+	)
+}
+
+// Trigger a run of the scheduled hypertable's stages outside of
+// its cron timing.
+func (c *Client) RunHypertable(id string) error {
+	// logger := fwhelpers.GetLogger()
+
+	method := "POST"
+	url := c.Host + "/api/v1/catalog/hypertable/" + id + "/run"
+
+	b, statusCode, _, _, _, err := c.doRequest(method, url, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if statusCode >= 200 && statusCode <= 299 {
+		return nil
+	} else {
+		msg, err := c.getAPIError(b)
+		if err != nil {
+			return err
+		} else {
+			return fmt.Errorf(msg)
+		}
+	}
+}
+
+// Poll the stages until the run triggered after the given snapshot of
+// the hypertable is finished. Stages whose start time differs from the
+// snapshot belong to the run, the others have not started yet.
+// The first failed stage ends the wait with a *HypertableRunError.
+func (c *Client) WaitForHypertableRun(id string, before Hypertable, timeout time.Duration) (Hypertable, error) {
+	started := map[int64]string{}
+	for _, stage := range before.Stages {
+		started[stage.ID] = stage.StartTime
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		hypertable, err := c.ReadHypertable(id)
+		if err != nil {
+			return hypertable, err
+		}
+
+		done := true
+		for _, stage := range hypertable.Stages {
+			current := stage.StartTime != "" && stage.StartTime != started[stage.ID]
+			switch {
+			case !current:
+				done = false
+			case stage.Failed():
+				return hypertable, &HypertableRunError{
+					Stage:  stage.Name,
+					Status: stage.RunStatus,
+					Errors: stage.Errors,
+				}
+			case !stage.Succeeded():
+				done = false
+			}
+		}
+		if done {
+			return hypertable, nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return hypertable, fmt.Errorf(
+				"hypertable run did not finish within %s", timeout,
+			)
+		}
+		if remaining > runPollInterval {
+			remaining = runPollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// A past or ongoing run of a scheduled hypertable.
+type HypertableRun struct {
+	Id           string               `json:"id"`
+	HypertableId string               `json:"hypertableId"`
+	Status       string               `json:"status"`
+	StartTime    string               `json:"startTime"`
+	EndTime      string               `json:"endTime,omitempty"`
+	Duration     string               `json:"duration,omitempty"`
+	Stages       []HypertableRunStage `json:"stages"`
+}
+
+type HypertableRunStage struct {
+	ID            int64    `json:"id"`
+	Name          string   `json:"name"`
+	ShortName     string   `json:"shortName"`
+	RunStatus     string   `json:"runStatus"`
+	StartTime     string   `json:"startTime,omitempty"`
+	Duration      string   `json:"duration,omitempty"`
+	RowCount      int64    `json:"rowCount"`
+	Errors        int64    `json:"errors"`
+	ErrorMessages []string `json:"errorMessages,omitempty"`
+}
+
+type HypertableRunPage struct {
+	Content       []HypertableRun `json:"content"`
+	Number        int             `json:"number"`
+	Size          int             `json:"size"`
+	TotalPages    int             `json:"totalPages"`
+	TotalElements int64           `json:"totalElements"`
+	Last          bool            `json:"last"`
+}
+
+// Server side filters and paging for the run history.
+// From and To are RFC 3339 timestamps bounding the run start time,
+// Status is one of the RunStatus values. Empty filters are not sent.
+type RunHistoryOptions struct {
+	From   string
+	To     string
+	Status string
+	Page   int
+	Size   int
+}
+
+func (o RunHistoryOptions) query() string {
+	q := url.Values{}
+	if o.From != "" {
+		q.Set("from", o.From)
+	}
+	if o.To != "" {
+		q.Set("to", o.To)
+	}
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+
+	size := o.Size
+	if size <= 0 {
+		size = listPageSize
+	}
+	q.Set("page", strconv.Itoa(o.Page))
+	q.Set("size", strconv.Itoa(size))
+
+	return q.Encode()
+}
+
+// List the runs of a hypertable, most recent first.
+func (c *Client) ListHypertableRuns(id string, opts RunHistoryOptions) (HypertableRunPage, error) {
+	// logger := fwhelpers.GetLogger()
+
+	method := "GET"
+	url := c.Host + "/api/v1/catalog/hypertable/" + id + "/runs?" + opts.query()
+
+	b, statusCode, _, _, _, err := c.doRequest(method, url, nil, nil)
+	if err != nil {
+		return HypertableRunPage{}, err
+	}
+
+	runPage := HypertableRunPage{}
+	if statusCode >= 200 && statusCode <= 299 {
+		err = json.Unmarshal(b, &runPage)
+		return runPage, err
+	} else {
+		msg, err := c.getAPIError(b)
+		if err != nil {
+			return runPage, err
+		} else {
+			return runPage, fmt.Errorf(msg)
+		}
+	}
+}
+
+func (c *Client) ListAllHypertableRuns(id string, opts RunHistoryOptions) ([]HypertableRun, error) {
+	items := []HypertableRun{}
+
+	if opts.Size <= 0 {
+		opts.Size = listPageSize
+	}
+	for {
+		runPage, err := c.ListHypertableRuns(id, opts)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, runPage.Content...)
+
+		opts.Page++
+		if runPage.Last || len(runPage.Content) == 0 || opts.Page >= runPage.TotalPages {
+			return items, nil
+		}
+	}
+}
@@ -7,9 +7,13 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
+// Number of items requested per page from the list endpoints.
+const listPageSize = 100
+
 type Client struct {
 	HTTPClient       *http.Client `json:"-"`
 	Host             string       `json:"-"`
@@ -21,6 +25,10 @@ type Client struct {
 	TokenHeader      string       `json:"-"`
 	Session          string       `json:"-"`
 	Token            string       `json:"-"`
+
+	// Guards the session and token, requests may be sent concurrently.
+	auth  sync.Mutex
+	cache *readCache
 }
 
 func NewClient(host string, orgname string, email string, password string) (*Client, error) {
@@ -37,18 +45,21 @@ func NewClient(host string, orgname string, email string, password string) (*Cli
 		TokenHeader:      "X-XSRF-TOKEN",
 		Session:          "",
 		Token:            "",
+
+		cache: sharedReadCache(host, orgname, email),
 	}
 	return &c, nil
 }
 
 func (c *Client) doRequest(method string, url string, body []byte, headers map[string]string) ([]byte, int, string, map[string][]string, map[string]string, error) {
+	// Login requests are sent while holding the auth lock.
+	isLogin := strings.Contains(url, "/login")
+
 	// Attempt login (for non-login requests only), if token is unset.
-	if !strings.Contains(url, "/login") {
-		if c.Session == "" || c.Token == "" {
-			err := c.doLogin()
-			if err != nil {
-				return nil, 500, "500 Internal Server Error", nil, nil, err
-			}
+	if !isLogin {
+		err := c.login("")
+		if err != nil {
+			return nil, 500, "500 Internal Server Error", nil, nil, err
 		}
 	}
 
@@ -56,6 +67,13 @@ func (c *Client) doRequest(method string, url string, body []byte, headers map[s
 	retryLogin := false
 
 DO_REQUEST:
+	// Credentials are read through the auth lock, the login request
+	// is sent while holding it and needs none.
+	var session, token string
+	if !isLogin {
+		session, token = c.credentials()
+	}
+
 	// Create request.
 	payload := bytes.NewBuffer(body)
 
@@ -73,21 +91,21 @@ DO_REQUEST:
 	if !strings.Contains(url, "/hypertable/activate") {
 		req.Header.Add("Content-Type", "application/json")
 	}
-	if !strings.Contains(url, "/login") {
-		req.Header.Add(c.TokenHeader, c.Token)
-	}
+	if !isLogin {
+		req.Header.Add(c.TokenHeader, token)
 
-	// Add cookies.
-	sessionCookie := &http.Cookie{
-		Name:  c.SessionCookie,
-		Value: c.Session,
-	}
-	tokenCookie := &http.Cookie{
-		Name:  c.TokenCookie,
-		Value: c.Token,
+		// Add cookies.
+		sessionCookie := &http.Cookie{
+			Name:  c.SessionCookie,
+			Value: session,
+		}
+		tokenCookie := &http.Cookie{
+			Name:  c.TokenCookie,
+			Value: token,
+		}
+		req.AddCookie(sessionCookie)
+		req.AddCookie(tokenCookie)
 	}
-	req.AddCookie(sessionCookie)
-	req.AddCookie(tokenCookie)
 
 	// Send request.
 	res, err := c.HTTPClient.Do(req)
@@ -103,8 +121,8 @@ DO_REQUEST:
 	// Attempt relogin (for non-login requests only) only once, if
 	// original request failed.
 	if res.StatusCode == 401 {
-		if !strings.Contains(url, "/login") && !retryLogin {
-			err := c.doLogin()
+		if !isLogin && !retryLogin {
+			err := c.login(token)
 			if err != nil {
 				return nil, 500, "500 Internal Server Error", nil, nil, err
 			}
@@ -125,6 +143,24 @@ DO_REQUEST:
 	return b, res.StatusCode, res.Status, res.Header, cookies, nil
 }
 
+func (c *Client) credentials() (string, string) {
+	c.auth.Lock()
+	defer c.auth.Unlock()
+	return c.Session, c.Token
+}
+
+// Log in unless logged in already, or logged in again since the stale
+// token was sent. Concurrent requests thus share a single login.
+func (c *Client) login(staleToken string) error {
+	c.auth.Lock()
+	defer c.auth.Unlock()
+
+	if c.Session != "" && c.Token != "" && c.Token != staleToken {
+		return nil
+	}
+	return c.doLogin()
+}
+
 func (c *Client) doLogin() error {
 	method := "POST"
 	url := c.Host + "/api/v1/account/login"
@@ -133,7 +169,7 @@ func (c *Client) doLogin() error {
 		Email:            c.Email,
 		Password:         c.Password,
 	}
-	body, err := json.Marshal(payload)
+	body, err := json.Marshal(&payload)
 	if err != nil {
 		return err
 	}
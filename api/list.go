@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// Server side filters and paging for the catalog list endpoints.
+// Empty filters are not sent.
+type ListOptions struct {
+	Name        string
+	ShortName   string
+	Tag         string
+	Admin       string
+	RefreshMode string // Applies to hypertables only.
+	Page        int
+	Size        int
+}
+
+func (o ListOptions) query() string {
+	q := url.Values{}
+	if o.Name != "" {
+		q.Set("name", o.Name)
+	}
+	if o.ShortName != "" {
+		q.Set("shortName", o.ShortName)
+	}
+	if o.Tag != "" {
+		q.Set("tag", o.Tag)
+	}
+	if o.Admin != "" {
+		q.Set("admin", o.Admin)
+	}
+	if o.RefreshMode != "" {
+		q.Set("refreshMode", o.RefreshMode)
+	}
+
+	size := o.Size
+	if size <= 0 {
+		size = listPageSize
+	}
+	q.Set("page", strconv.Itoa(o.Page))
+	q.Set("size", strconv.Itoa(size))
+
+	return q.Encode()
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type PolicyKind string
+
+const (
+	PolicyKindAccessControl PolicyKind = "accessControl"
+	PolicyKindDataMask      PolicyKind = "dataMask"
+	PolicyKindRowFilter     PolicyKind = "rowFilter"
+)
+
+const (
+	MemberTypeUser  = "user"
+	MemberTypeGroup = "group"
+)
+
+// Policy is a flattened access control, data mask or row filter
+// entry of a hypertable.
+type Policy struct {
+	Kind             PolicyKind
+	PolicyId         string
+	HypertableId     string
+	MemberType       string
+	Member           string
+	Column           string
+	MaskingOption    string
+	FilterExpression string
+}
+
+// Identifies a policy resource in state. Column is empty for access
+// control policies. MemberType is empty for state IDs in the legacy
+// format, which only carry the member.
+type PolicyStateId struct {
+	HypertableId string
+	MemberType   string
+	Member       string
+	Column       string
+}
+
+// Prefix of state IDs with escaped segments. Members and columns may
+// contain colons, hence every segment is percent-encoded.
+const policyStateIdVersion = "v2"
+
+// State IDs are "v2:hypertableId:memberType:member[:column]" with
+// percent-encoded segments.
+func formatPolicyStateId(id PolicyStateId, withColumn bool) string {
+	if id.HypertableId == "" || id.MemberType == "" || id.Member == "" ||
+		(withColumn && id.Column == "") {
+		return ""
+	}
+
+	parts := []string{policyStateIdVersion, id.HypertableId, id.MemberType, id.Member}
+	if withColumn {
+		parts = append(parts, id.Column)
+	}
+	for i := 1; i < len(parts); i++ {
+		parts[i] = url.QueryEscape(parts[i])
+	}
+	return strings.Join(parts, ":")
+}
+
+// Parses state IDs of the current format as well as the unescaped
+// legacy formats "hypertableId:memberType:member[:column]" and
+// "hypertableId:member[:column]", which lacks the member type.
+func parsePolicyStateId(stateId string, withColumn bool) (PolicyStateId, error) {
+	id := PolicyStateId{}
+	parts := strings.Split(stateId, ":")
+
+	n := 2
+	if withColumn {
+		n = 3
+	}
+	switch {
+	case len(parts) == n+2 && parts[0] == policyStateIdVersion:
+		for i := 1; i < len(parts); i++ {
+			part, err := url.QueryUnescape(parts[i])
+			if err != nil {
+				return id, fmt.Errorf("invalid state ID %q: %s", stateId, err)
+			}
+			parts[i] = part
+		}
+		id.HypertableId, id.MemberType, id.Member = parts[1], parts[2], parts[3]
+		if id.MemberType != MemberTypeUser && id.MemberType != MemberTypeGroup {
+			return id, fmt.Errorf("invalid member type in state ID %q", stateId)
+		}
+	case len(parts) == n+1 && (parts[1] == MemberTypeUser || parts[1] == MemberTypeGroup):
+		id.HypertableId, id.MemberType, id.Member = parts[0], parts[1], parts[2]
+	case len(parts) == n:
+		id.HypertableId, id.Member = parts[0], parts[1]
+	default:
+		return id, fmt.Errorf("invalid state ID %q", stateId)
+	}
+	if withColumn {
+		id.Column = parts[len(parts)-1]
+	}
+
+	if id.HypertableId == "" || id.Member == "" || (withColumn && id.Column == "") {
+		return id, fmt.Errorf("invalid state ID %q", stateId)
+	}
+	return id, nil
+}
+
+// FindHypertablePolicy returns the policy identified by the state ID,
+// or ErrNotFound. Without a member type, as in legacy state IDs, the
+// member is looked up among both users and groups.
+func (c *Client) FindHypertablePolicy(kind PolicyKind, id PolicyStateId) (Policy, error) {
+	policies, err := c.listHypertablePolicies(id.HypertableId, PolicyListOptions{
+		Kinds:      []PolicyKind{kind},
+		MemberType: id.MemberType,
+		Member:     id.Member,
+	})
+	if err != nil {
+		return Policy{}, err
+	}
+
+	matches := []Policy{}
+	for _, p := range policies {
+		if p.Column == id.Column {
+			matches = append(matches, p)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Policy{}, ErrNotFound
+	case 1:
+		return matches[0], nil
+	default:
+		return Policy{}, fmt.Errorf(
+			"%q is both a user and a group of hypertable %s, "+
+				"the member type needs to be given",
+			id.Member, id.HypertableId,
+		)
+	}
+}
+
+// Hypertables are filtered server side using the embedded list
+// options. The policy endpoints are per hypertable and do not
+// support filtering, hence the rest is filtered client side.
+type PolicyListOptions struct {
+	ListOptions
+
+	Kinds      []PolicyKind
+	MemberType string
+	Member     string
+}
+
+func (o PolicyListOptions) matches(p Policy) bool {
+	if o.MemberType != "" && o.MemberType != p.MemberType {
+		return false
+	}
+	if o.Member != "" && o.Member != p.Member {
+		return false
+	}
+	return true
+}
+
+func (o PolicyListOptions) includes(kind PolicyKind) bool {
+	if len(o.Kinds) == 0 {
+		return true
+	}
+	for _, k := range o.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ListPolicies lists the policies of every hypertable matching
+// the options.
+func (c *Client) ListPolicies(opts PolicyListOptions) ([]Policy, error) {
+	policies := []Policy{}
+
+	it := c.IterateHypertables(opts.ListOptions)
+	for it.Next() {
+		ht := it.Hypertable()
+		if ht.Deleted {
+			continue
+		}
+
+		htPolicies, err := c.listHypertablePolicies(ht.Id, opts)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, htPolicies...)
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+
+	return policies, nil
+}
+
+// ListHypertablePolicies lists access control, data mask and row
+// filter policies of a single hypertable, in that order.
+func (c *Client) ListHypertablePolicies(hypertableId string) ([]Policy, error) {
+	return c.listHypertablePolicies(hypertableId, PolicyListOptions{})
+}
+
+func (c *Client) listHypertablePolicies(hypertableId string, opts PolicyListOptions) ([]Policy, error) {
+	all := []Policy{}
+
+	if opts.includes(PolicyKindAccessControl) {
+		htACL, err := c.ReadHypertableAccessControl(hypertableId)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, htACL.Policies()...)
+	}
+
+	if opts.includes(PolicyKindDataMask) {
+		htDataMasks, err := c.ReadHypertableDataMask(hypertableId)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, htDataMasks.Policies()...)
+	}
+
+	if opts.includes(PolicyKindRowFilter) {
+		htRowFilters, err := c.ReadHypertableRowFilter(hypertableId)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, htRowFilters.Policies()...)
+	}
+
+	policies := []Policy{}
+	for _, p := range all {
+		p.HypertableId = hypertableId
+		if opts.matches(p) {
+			policies = append(policies, p)
+		}
+	}
+	return policies, nil
+}
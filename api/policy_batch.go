@@ -0,0 +1,169 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Number of requests a batch sends concurrently. The policy endpoints
+// have no bulk variants, hence batches are sent by a bounded pool of
+// workers rather than one request at a time.
+const batchWorkers = 8
+
+// Failure of a single item of a batch.
+type BatchItemError struct {
+	Index int
+	Item  string
+	Err   error
+}
+
+func (e BatchItemError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Item, e.Err)
+}
+
+// BatchError aggregates the failures of a batch in the order of the
+// items, one bad item does not hide the others.
+type BatchError struct {
+	Op     string
+	Total  int
+	Errors []BatchItemError
+}
+
+func (e *BatchError) Error() string {
+	msgs := []string{}
+	for _, ie := range e.Errors {
+		msgs = append(msgs, "  "+ie.Error())
+	}
+	return fmt.Sprintf(
+		"failed to %s %d of %d:\n%s", e.Op, len(e.Errors), e.Total, strings.Join(msgs, "\n"),
+	)
+}
+
+// Run do for every item with at most batchWorkers at a time. All items
+// are attempted, the failures are returned as a BatchError.
+func runBatch(op string, items []string, do func(i int) error) error {
+	errs := make([]error, len(items))
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < batchWorkers && w < len(items); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = do(i)
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	batchErr := &BatchError{Op: op, Total: len(items)}
+	for i, err := range errs {
+		if err != nil {
+			batchErr.Errors = append(batchErr.Errors, BatchItemError{Index: i, Item: items[i], Err: err})
+		}
+	}
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+func describeMember(userEmail string, groupName string) string {
+	if userEmail != "" {
+		return fmt.Sprintf("%s %q", MemberTypeUser, userEmail)
+	}
+	return fmt.Sprintf("%s %q", MemberTypeGroup, groupName)
+}
+
+func describeAccessControls(payloads []HypertableAccessControl) []string {
+	items := []string{}
+	for _, p := range payloads {
+		items = append(items, fmt.Sprintf(
+			"%s on hypertable %s", describeMember(p.UserEmail, p.GroupName), p.HypertableId,
+		))
+	}
+	return items
+}
+
+func describeDataMasks(payloads []HypertableDataMask) []string {
+	items := []string{}
+	for _, p := range payloads {
+		items = append(items, fmt.Sprintf(
+			"%s on column %q of hypertable %s",
+			describeMember(p.UserEmail, p.GroupName), p.Column, p.HypertableId,
+		))
+	}
+	return items
+}
+
+func describeRowFilters(payloads []HypertableRowFilter) []string {
+	items := []string{}
+	for _, p := range payloads {
+		items = append(items, fmt.Sprintf(
+			"%s on column %q of hypertable %s",
+			describeMember(p.UserEmail, p.GroupName), p.Column, p.HypertableId,
+		))
+	}
+	return items
+}
+
+// Policy creation reports success in the response body.
+func checkCreated(status string, err error) error {
+	if err != nil {
+		return err
+	}
+	if status != "true" {
+		return fmt.Errorf("not created")
+	}
+	return nil
+}
+
+// CreateHypertableAccessControls grants access for every payload.
+func (c *Client) CreateHypertableAccessControls(payloads []HypertableAccessControl) error {
+	return runBatch("grant access", describeAccessControls(payloads), func(i int) error {
+		return checkCreated(c.CreateHypertableAccessControl(payloads[i]))
+	})
+}
+
+// DeleteHypertableAccessControls revokes access for every payload.
+func (c *Client) DeleteHypertableAccessControls(payloads []HypertableAccessControl) error {
+	return runBatch("revoke access", describeAccessControls(payloads), func(i int) error {
+		return c.DeleteHypertableAccessControl(payloads[i])
+	})
+}
+
+// CreateHypertableDataMasks creates or updates the data mask of every
+// payload.
+func (c *Client) CreateHypertableDataMasks(payloads []HypertableDataMask) error {
+	return runBatch("set data masks", describeDataMasks(payloads), func(i int) error {
+		return checkCreated(c.CreateHypertableDataMask(payloads[i]))
+	})
+}
+
+// DeleteHypertableDataMasks deletes the data mask of every payload.
+func (c *Client) DeleteHypertableDataMasks(payloads []HypertableDataMask) error {
+	return runBatch("delete data masks", describeDataMasks(payloads), func(i int) error {
+		return c.DeleteHypertableDataMask(payloads[i])
+	})
+}
+
+// CreateHypertableRowFilters creates or updates the row filter of every
+// payload.
+func (c *Client) CreateHypertableRowFilters(payloads []HypertableRowFilter) error {
+	return runBatch("set row filters", describeRowFilters(payloads), func(i int) error {
+		return checkCreated(c.CreateHypertableRowFilter(payloads[i]))
+	})
+}
+
+// DeleteHypertableRowFilters deletes the row filter of every payload.
+func (c *Client) DeleteHypertableRowFilters(payloads []HypertableRowFilter) error {
+	return runBatch("delete row filters", describeRowFilters(payloads), func(i int) error {
+		return c.DeleteHypertableRowFilter(payloads[i])
+	})
+}
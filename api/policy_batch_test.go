@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	items := []string{}
+	for i := 0; i < 50; i++ {
+		items = append(items, fmt.Sprintf("item %d", i))
+	}
+
+	var running, maxRunning, calls int32
+	attempted := make([]bool, len(items))
+	err := runBatch("test", items, func(i int) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+
+		atomic.AddInt32(&calls, 1)
+		attempted[i] = true
+		if i%7 == 3 {
+			return fmt.Errorf("failed %d", i)
+		}
+		return nil
+	})
+
+	if calls != int32(len(items)) {
+		t.Errorf("do called %d times, want %d", calls, len(items))
+	}
+	for i, ok := range attempted {
+		if !ok {
+			t.Errorf("item %d not attempted", i)
+		}
+	}
+	if maxRunning > batchWorkers {
+		t.Errorf("%d items ran concurrently, want at most %d", maxRunning, batchWorkers)
+	}
+
+	batchErr := &BatchError{}
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("runBatch = %v, want a BatchError", err)
+	}
+	if batchErr.Op != "test" || batchErr.Total != len(items) {
+		t.Errorf("BatchError op %q total %d, want test %d", batchErr.Op, batchErr.Total, len(items))
+	}
+	indexes := []int{}
+	for _, ie := range batchErr.Errors {
+		indexes = append(indexes, ie.Index)
+		if want := fmt.Sprintf("failed %d", ie.Index); ie.Err.Error() != want || ie.Item != items[ie.Index] {
+			t.Errorf("error of item %d = %s: %s, want %s: %s", ie.Index, ie.Item, ie.Err, items[ie.Index], want)
+		}
+	}
+	if want := []int{3, 10, 17, 24, 31, 38, 45}; !reflect.DeepEqual(indexes, want) {
+		t.Errorf("failed indexes = %v, want %v in order", indexes, want)
+	}
+}
+
+func TestRunBatchSucceeded(t *testing.T) {
+	if err := runBatch("test", nil, func(i int) error { return nil }); err != nil {
+		t.Errorf("runBatch without items = %v", err)
+	}
+	if err := runBatch("test", []string{"a", "b"}, func(i int) error { return nil }); err != nil {
+		t.Errorf("runBatch = %v", err)
+	}
+}
+
+func TestBatchErrorMessage(t *testing.T) {
+	err := &BatchError{Op: "grant access", Total: 3, Errors: []BatchItemError{
+		{Index: 0, Item: `user "a"`, Err: fmt.Errorf("denied")},
+		{Index: 2, Item: `group "c"`, Err: fmt.Errorf("not created")},
+	}}
+
+	want := "failed to grant access 2 of 3:\n" +
+		"  user \"a\": denied\n" +
+		"  group \"c\": not created"
+	if err.Error() != want {
+		t.Errorf("BatchError = %q, want %q", err.Error(), want)
+	}
+}
+
+// Grants are sent concurrently while the session expires, the
+// requests share a single login and the bad members are reported.
+func TestCreateHypertableAccessControlsConcurrently(t *testing.T) {
+	var mu sync.Mutex
+	logins, requests := 0, 0
+	token := ""
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if strings.HasSuffix(r.URL.Path, "/account/login") {
+			logins++
+			token = fmt.Sprintf("token%d", logins)
+			http.SetCookie(w, &http.Cookie{Name: "SESSION", Value: "session"})
+			http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: token})
+			return
+		}
+
+		// The first session expires after a few requests.
+		requests++
+		if requests == 5 && logins == 1 {
+			token = "expired"
+		}
+		if r.Header.Get("X-XSRF-TOKEN") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		payload := HypertableAccessControl{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if strings.HasPrefix(payload.GroupName, "bad") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":400,"error":"Bad Request"}`))
+			return
+		}
+		w.Write([]byte("true"))
+	}))
+	defer s.Close()
+
+	c, err := NewClient(s.URL, "org", "batch@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payloads := []HypertableAccessControl{}
+	for i := 0; i < 30; i++ {
+		group := fmt.Sprintf("group%d", i)
+		if i%10 == 9 {
+			group = fmt.Sprintf("bad%d", i)
+		}
+		payloads = append(payloads, HypertableAccessControl{HypertableId: "ht1", GroupName: group})
+	}
+
+	err = c.CreateHypertableAccessControls(payloads)
+
+	batchErr := &BatchError{}
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("CreateHypertableAccessControls = %v, want a BatchError", err)
+	}
+	items := []string{}
+	for _, ie := range batchErr.Errors {
+		items = append(items, ie.Error())
+	}
+	want := []string{
+		`group "bad9" on hypertable ht1: 400 Bad Request`,
+		`group "bad19" on hypertable ht1: 400 Bad Request`,
+		`group "bad29" on hypertable ht1: 400 Bad Request`,
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("failed items = %q, want %q", items, want)
+	}
+	if logins != 2 {
+		t.Errorf("logged in %d times, want twice", logins)
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"testing"
+)
+
+func FuzzPolicyStateId(f *testing.F) {
+	f.Add("ht1", true, "alice@example.com", "email", true)
+	f.Add("ht1", false, "data:engineers", "", false)
+	f.Add("v2", true, "a:b", "c:d", true)
+	f.Add("ht 1", false, "50% off+more", "col name", true)
+	f.Add("ht%3A1", true, "%2B", "+", true)
+	f.Add("ht1", false, "::", ":", true)
+	f.Add("ht1", true, "ünïcødé", "列", true)
+
+	f.Fuzz(func(t *testing.T, hypertableId string, isUser bool, member string, column string, withColumn bool) {
+		id := PolicyStateId{
+			HypertableId: hypertableId,
+			MemberType:   MemberTypeGroup,
+			Member:       member,
+		}
+		if isUser {
+			id.MemberType = MemberTypeUser
+		}
+		if withColumn {
+			id.Column = column
+		}
+
+		stateId := formatPolicyStateId(id, withColumn)
+		if stateId == "" {
+			if hypertableId != "" && member != "" && (!withColumn || column != "") {
+				t.Fatalf("formatPolicyStateId(%+v) is empty", id)
+			}
+			return
+		}
+
+		got, err := parsePolicyStateId(stateId, withColumn)
+		if err != nil {
+			t.Fatalf("parsePolicyStateId(%q): %s", stateId, err)
+		}
+		if got != id {
+			t.Fatalf("parsePolicyStateId(formatPolicyStateId(%+v)) = %+v", id, got)
+		}
+	})
+}
+
+func TestParsePolicyStateId(t *testing.T) {
+	tests := []struct {
+		stateId    string
+		withColumn bool
+		want       PolicyStateId
+	}{
+		// Current format.
+		{"v2:ht1:user:alice%40example.com", false,
+			PolicyStateId{"ht1", MemberTypeUser, "alice@example.com", ""}},
+		{"v2:ht1:group:data%3Aengineers:col%3A1", true,
+			PolicyStateId{"ht1", MemberTypeGroup, "data:engineers", "col:1"}},
+		{"v2:ht1:group:data+team:col", true,
+			PolicyStateId{"ht1", MemberTypeGroup, "data team", "col"}},
+
+		// Legacy format with member type.
+		{"ht1:user:alice@example.com", false,
+			PolicyStateId{"ht1", MemberTypeUser, "alice@example.com", ""}},
+		{"ht1:group:engineers:email", true,
+			PolicyStateId{"ht1", MemberTypeGroup, "engineers", "email"}},
+		{"ht1:group:a%20b:c+d", true,
+			PolicyStateId{"ht1", MemberTypeGroup, "a%20b", "c+d"}},
+
+		// Legacy format without member type.
+		{"ht1:alice@example.com", false,
+			PolicyStateId{"ht1", "", "alice@example.com", ""}},
+		{"ht1:engineers:email", true,
+			PolicyStateId{"ht1", "", "engineers", "email"}},
+
+		// Legacy IDs of a hypertable with ID "v2".
+		{"v2:alice@example.com", false,
+			PolicyStateId{"v2", "", "alice@example.com", ""}},
+		{"v2:user:alice@example.com", false,
+			PolicyStateId{"v2", MemberTypeUser, "alice@example.com", ""}},
+		{"v2:engineers:email", true,
+			PolicyStateId{"v2", "", "engineers", "email"}},
+		{"v2:group:engineers:email", true,
+			PolicyStateId{"v2", MemberTypeGroup, "engineers", "email"}},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePolicyStateId(tt.stateId, tt.withColumn)
+		if err != nil {
+			t.Errorf("parsePolicyStateId(%q): %s", tt.stateId, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePolicyStateId(%q) = %+v, want %+v", tt.stateId, got, tt.want)
+		}
+	}
+}
+
+func TestParsePolicyStateIdInvalid(t *testing.T) {
+	tests := []struct {
+		stateId    string
+		withColumn bool
+	}{
+		{"", false},
+		{"ht1", false},
+		{"ht1:", false},
+		{":alice", false},
+		{"ht1:a:b:c:d", false},
+		{"ht1:engineers", true},
+		{"ht1:engineers:", true},
+		{"v2:ht1:admin:alice", false},
+		{"v2:ht1:user:%zz", false},
+		{"v2:ht1:user:alice:col:x", true},
+	}
+
+	for _, tt := range tests {
+		if _, err := parsePolicyStateId(tt.stateId, tt.withColumn); err == nil {
+			t.Errorf("parsePolicyStateId(%q) succeeded, want an error", tt.stateId)
+		}
+	}
+}
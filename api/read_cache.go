@@ -0,0 +1,112 @@
+package api
+
+import (
+	"strings"
+	"sync"
+)
+
+// Caches the per hypertable reads of an account, i.e. its policies and
+// columns, so that every resource on a hypertable is served by one
+// fetch. Every resource type configures its own client, hence the
+// clients of an account share the cache, and writes through any of
+// them invalidate the hypertable's entries for all.
+type readCache struct {
+	mu      sync.Mutex
+	entries map[readCacheKey]*readCacheEntry
+}
+
+// Kinds of cached reads besides the policy kinds.
+const cacheKindColumns = "columns"
+
+type readCacheKey struct {
+	Kind         string
+	HypertableId string
+}
+
+type readCacheEntry struct {
+	ready chan struct{}
+	value interface{}
+	err   error
+}
+
+func newReadCache() *readCache {
+	return &readCache{entries: map[readCacheKey]*readCacheEntry{}}
+}
+
+var (
+	sharedReadCachesMu sync.Mutex
+	sharedReadCaches   = map[string]*readCache{}
+)
+
+// Cache shared by the clients of the account.
+func sharedReadCache(host string, orgname string, email string) *readCache {
+	sharedReadCachesMu.Lock()
+	defer sharedReadCachesMu.Unlock()
+
+	key := strings.Join([]string{host, orgname, email}, "\x00")
+	rc, ok := sharedReadCaches[key]
+	if !ok {
+		rc = newReadCache()
+		sharedReadCaches[key] = rc
+	}
+	return rc
+}
+
+// Return the cached value or fetch it. Concurrent reads of the same
+// hypertable wait for a single fetch, failures are not cached.
+func (rc *readCache) get(kind string, hypertableId string, fetch func() (interface{}, error)) (interface{}, error) {
+	if rc == nil {
+		return fetch()
+	}
+
+	key := readCacheKey{Kind: kind, HypertableId: hypertableId}
+
+	rc.mu.Lock()
+	e, ok := rc.entries[key]
+	if ok {
+		rc.mu.Unlock()
+		<-e.ready
+		return e.value, e.err
+	}
+	e = &readCacheEntry{ready: make(chan struct{})}
+	rc.entries[key] = e
+	rc.mu.Unlock()
+
+	e.value, e.err = fetch()
+	close(e.ready)
+
+	if e.err != nil {
+		rc.invalidateEntry(key, e)
+	}
+	return e.value, e.err
+}
+
+func (rc *readCache) invalidate(kind string, hypertableId string) {
+	if rc == nil {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	delete(rc.entries, readCacheKey{Kind: kind, HypertableId: hypertableId})
+}
+
+// Invalidate every kind of read of the hypertable.
+func (rc *readCache) invalidateHypertable(hypertableId string) {
+	kinds := []string{
+		string(PolicyKindAccessControl), string(PolicyKindDataMask),
+		string(PolicyKindRowFilter), cacheKindColumns,
+	}
+	for _, kind := range kinds {
+		rc.invalidate(kind, hypertableId)
+	}
+}
+
+// Drop the entry unless it was replaced meanwhile.
+func (rc *readCache) invalidateEntry(key readCacheKey, e *readCacheEntry) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.entries[key] == e {
+		delete(rc.entries, key)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server logging in any account, the other requests are served by the
+// handler.
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/account/login") {
+			http.SetCookie(w, &http.Cookie{Name: "SESSION", Value: "session"})
+			http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: "token"})
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestClient(t *testing.T, s *httptest.Server) *Client {
+	t.Helper()
+
+	c, err := NewClient(s.URL, "org", "admin@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestReadCacheSharedAcrossClients(t *testing.T) {
+	mu := sync.Mutex{}
+	columns := `[{"name":"region","type":"varchar"}]`
+	reads := 0
+
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v1/catalog/hypertable/ht1/columns":
+			reads++
+			w.Write([]byte(columns))
+		case r.Method == "PUT" && r.URL.Path == "/api/v1/catalog/hypertable/ht1":
+			columns = `[{"name":"region","type":"varchar"},{"name":"email","type":"varchar"}]`
+			w.Write([]byte(`{"id":"ht1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	// Clients of different resource types, e.g. a live hypertable and
+	// a masking policy.
+	hypertables := newTestClient(t, s)
+	policies := newTestClient(t, s)
+
+	got, err := policies.ReadHypertableColumns("ht1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d columns, want 1", len(got))
+	}
+	if _, err := hypertables.ReadHypertableColumns("ht1"); err != nil {
+		t.Fatal(err)
+	}
+	if reads != 1 {
+		t.Errorf("columns read %d times, want once", reads)
+	}
+
+	if _, err := hypertables.UpdateHypertable("ht1", Hypertable{}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = policies.ReadHypertableColumns("ht1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("got %d columns after the update through another client, want 2", len(got))
+	}
+	if reads != 2 {
+		t.Errorf("columns read %d times, want twice", reads)
+	}
+}
+
+func TestReadCachePerAccount(t *testing.T) {
+	reads := 0
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		reads++
+		w.Write([]byte(`[]`))
+	})
+
+	a, err := NewClient(s.URL, "org", "a@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewClient(s.URL, "org", "b@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*Client{a, b} {
+		if _, err := c.ReadHypertableColumns("ht1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if reads != 2 {
+		t.Errorf("columns read %d times, want once per account", reads)
+	}
+}
+
+func TestReadCacheFailuresNotCached(t *testing.T) {
+	rc := newReadCache()
+
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return nil, ErrNotFound
+		}
+		return "ok", nil
+	}
+
+	if _, err := rc.get(cacheKindColumns, "ht1", fetch); err == nil {
+		t.Fatal("first fetch succeeded, want an error")
+	}
+	v, err := rc.get(cacheKindColumns, "ht1", fetch)
+	if err != nil || v != "ok" {
+		t.Fatalf("get = %v, %v, want ok", v, err)
+	}
+	if _, err := rc.get(cacheKindColumns, "ht1", fetch); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("fetched %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type SQLValidationRequest struct {
+	Sql string `json:"sql"`
+}
+
+// Result of validating a query without running it.
+type SQLValidation struct {
+	Valid   bool                 `json:"valid"`
+	Errors  []SQLValidationError `json:"errors,omitempty"`
+	Columns []SQLColumn          `json:"columns,omitempty"`
+}
+
+// Problem found in a query. Line and column are 1-based positions in
+// the query, zero if unknown.
+type SQLValidationError struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+// Kinds of SQL validation errors reported by the server.
+const (
+	SQLErrorSyntax        = "SYNTAX"
+	SQLErrorUnknownTable  = "UNKNOWN_TABLE"
+	SQLErrorUnknownColumn = "UNKNOWN_COLUMN"
+)
+
+func (e SQLValidationError) String() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+	}
+	return e.Message
+}
+
+// Column of the result of a query.
+type SQLColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Parse and plan the query on the server, returning the errors found
+// or the columns of its result.
+func (c *Client) ValidateSQL(sql string) (SQLValidation, error) {
+	// logger := fwhelpers.GetLogger()
+
+	method := "POST"
+	url := c.Host + "/api/v1/catalog/hypertable/validate"
+	body, err := json.Marshal(SQLValidationRequest{Sql: sql})
+	if err != nil {
+		return SQLValidation{}, err
+	}
+
+	b, statusCode, _, _, _, err := c.doRequest(method, url, body, nil)
+	if err != nil {
+		return SQLValidation{}, err
+	}
+
+	validation := SQLValidation{}
+	if statusCode >= 200 && statusCode <= 299 {
+		err = json.Unmarshal(b, &validation)
+		return validation, err
+	} else {
+		msg, err := c.getAPIError(b)
+		if err != nil {
+			return validation, err
+		} else {
+			return validation, fmt.Errorf(msg)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package export
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zipstack/pct-plugin-framework/schema"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+	"github.com/zipstack/pct-provider-zipstack-cloud/plugin"
+)
+
+// Options for the export command.
+type Options struct {
+	Host             string
+	OrganisationName string
+	Email            string
+	Password         string
+	OutDir           string
+	IncludeSecrets   bool
+}
+
+// Tracks generated labels and resource addresses so that
+// dependent resources refer to each other instead of IDs.
+type exporter struct {
+	Client  *api.Client
+	Options Options
+
+	labels              map[string]map[string]bool
+	hypertableIds       []string
+	hypertableAddresses map[string]string
+	imports             []string
+}
+
+// Run executes the export command with the given command line
+// arguments and returns the process exit code.
+func Run(args []string) int {
+	opts := Options{}
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.StringVar(&opts.Host, "host", "", "Zipstack Cloud host, e.g. https://cloud.zipstack.com")
+	fs.StringVar(&opts.OrganisationName, "org", "", "Organisation name")
+	fs.StringVar(&opts.Email, "email", "", "Login email")
+	fs.StringVar(&opts.Password, "password", os.Getenv("ZIPSTACK_CLOUD_PASSWORD"), "Login password (defaults to $ZIPSTACK_CLOUD_PASSWORD)")
+	fs.StringVar(&opts.OutDir, "out", ".", "Directory to write the configuration files to")
+	fs.BoolVar(&opts.IncludeSecrets, "include-secrets", false, "Write datasource connection metadata into the configuration")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if opts.Host == "" || opts.OrganisationName == "" ||
+		opts.Email == "" || opts.Password == "" {
+		fmt.Fprintln(os.Stderr, "export: -host, -org, -email and -password are required")
+		fs.Usage()
+		return 2
+	}
+
+	client, err := api.NewClient(
+		opts.Host, opts.OrganisationName,
+		opts.Email, opts.Password,
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 1
+	}
+
+	err = Export(client, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// Export writes configuration for every datasource, hypertable and
+// hypertable policy of the organisation, along with an import script
+// which adopts the existing objects into state.
+func Export(client *api.Client, opts Options) error {
+	e := &exporter{
+		Client:              client,
+		Options:             opts,
+		labels:              map[string]map[string]bool{},
+		hypertableAddresses: map[string]string{},
+	}
+
+	err := os.MkdirAll(opts.OutDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name   string
+		render func() (string, error)
+	}{
+		{"provider.hcl", e.renderProvider},
+		{"datasources.hcl", e.renderDatasources},
+		{"hypertables.hcl", e.renderHypertables},
+		{"policies.hcl", e.renderPolicies},
+	}
+	for _, f := range files {
+		contents, err := f.render()
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(filepath.Join(opts.OutDir, f.name), []byte(contents), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	script := "#!/bin/sh\n" +
+		"# Imports the exported objects into state.\n" +
+		"set -e\n\n" +
+		strings.Join(e.imports, "\n") + "\n"
+
+	return os.WriteFile(filepath.Join(opts.OutDir, "import.sh"), []byte(script), 0755)
+}
+
+func (e *exporter) renderProvider() (string, error) {
+	attrs := []attribute{
+		{"host", hclString(e.Options.Host)},
+		{"organisationname", hclString(e.Options.OrganisationName)},
+		{"email", hclString(e.Options.Email)},
+		{"password", hclString("")},
+	}
+
+	return fmt.Sprintf(
+		"# Set the password before applying.\nprovider %s %s\n",
+		hclString(providerTypeName()), renderObject(attrs, 0),
+	), nil
+}
+
+func (e *exporter) renderDatasources() (string, error) {
+	typeName := resourceTypeName(plugin.NewDatasourceResource)
+
+	datasources, err := e.Client.ListAllDatasources(api.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list datasources: %s", err)
+	}
+
+	blocks := []string{}
+	for _, ds := range datasources {
+		if ds.Deleted {
+			continue
+		}
+
+		// Without secrets the attribute is left out rather than empty,
+		// an empty value would overwrite the connection metadata on
+		// apply. The configuration is incomplete until it is set.
+		connectionMetadata := hclComment(
+			"connection_metadata is not exported, set it before applying",
+		)
+		if e.Options.IncludeSecrets {
+			connectionMetadata = attribute{"connection_metadata", hclString(ds.ConnectionMetadata)}
+		}
+
+		label := e.label(typeName, ds.ShortName)
+		attrs := []attribute{
+			{"name", hclString(ds.Name)},
+			{"description", hclString(ds.Description)},
+			{"tags", hclStringList(ds.Tags)},
+			{"admins", hclStringList(ds.Admins)},
+			{"short_name", hclString(ds.ShortName)},
+			connectionMetadata,
+			{"db_connector", hclString(ds.DbConnector)},
+			{"db_sub_connector", hclString(ds.DbSubConnector)},
+			{"db_sub_connector_display_name", hclString(ds.DbSubConnectorDisplayName)},
+		}
+
+		blocks = append(blocks, renderResource(typeName, label, attrs))
+		e.addImport(typeName+"."+label, ds.Id)
+	}
+
+	return strings.Join(blocks, "\n"), nil
+}
+
+func (e *exporter) renderHypertables() (string, error) {
+	liveTypeName := resourceTypeName(plugin.NewHypertableLiveResource)
+	scheduledTypeName := resourceTypeName(plugin.NewHypertableScheduledResource)
+
+	hypertables, err := e.Client.ListAllHypertables(api.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list hypertables: %s", err)
+	}
+
+	blocks := []string{}
+	for _, ht := range hypertables {
+		if ht.Deleted {
+			continue
+		}
+
+		typeName := scheduledTypeName
+		if ht.SqlSelect != "" {
+			typeName = liveTypeName
+		}
+
+		label := e.label(typeName, ht.ShortName)
+		attrs := []attribute{
+			{"name", hclString(ht.Name)},
+			{"short_name", hclString(ht.ShortName)},
+			{"description", hclString(ht.Description)},
+			{"tags", hclStringList(ht.Tags)},
+			{"admins", hclStringList(ht.Admins)},
+			{"refresh_mode", hclString(ht.RefreshMode)},
+		}
+
+		if typeName == liveTypeName {
+			attrs = append(attrs, attribute{"sql_select", hclString(ht.SqlSelect)})
+		} else {
+			stages := [][]attribute{}
+			for _, hs := range ht.Stages {
+				stage := []attribute{
+					{"query", hclString(hs.Query)},
+					{"name", hclString(hs.Name)},
+					{"short_name", hclString(hs.ShortName)},
+					{"description", hclString(hs.Description)},
+				}
+				if len(hs.DependsOn) > 0 {
+					stage = append(stage, attribute{"depends_on", hclStringList(hs.DependsOn)})
+				}
+				stages = append(stages, stage)
+			}
+
+			attrs = append(attrs,
+				attribute{"cron_timing", hclString(ht.CronTiming)},
+				attribute{"timezone", hclString(ht.TimeZone)},
+				attribute{"stages", renderObjectList(stages, 1)},
+				attribute{"backing_table", hclString(ht.BackingTable)},
+				attribute{"backing_table_update_mode", hclString(ht.BackingTableUpdateMode)},
+				attribute{"primary_keys", hclStringList(ht.PrimaryKeys)},
+				attribute{"partition_keys", hclStringList(ht.PartitionKeys)},
+				attribute{"rest_endpoint", hclString(ht.RESTEndpoint)},
+				attribute{"status", hclBool(ht.Status)},
+			)
+		}
+
+		address := typeName + "." + label
+		e.hypertableIds = append(e.hypertableIds, ht.Id)
+		e.hypertableAddresses[ht.Id] = address
+
+		blocks = append(blocks, renderResource(typeName, label, attrs))
+		e.addImport(address, ht.Id)
+	}
+
+	return strings.Join(blocks, "\n"), nil
+}
+
+// Policies are rendered after the hypertables, so that they
+// can refer to the hypertable resources by address.
+func (e *exporter) renderPolicies() (string, error) {
+	aclTypeName := resourceTypeName(plugin.NewHypertableAccessControlResource)
+	maskTypeName := resourceTypeName(plugin.NewHypertableDataMaskResource)
+	filterTypeName := resourceTypeName(plugin.NewHypertableRowFilterResource)
+
+	blocks := []string{}
+	for _, htId := range e.hypertableIds {
+		htAddress := e.hypertableAddresses[htId]
+		htLabel := strings.SplitN(htAddress, ".", 2)[1]
+		htRef := hclRef(htAddress, "id")
+
+		policies, err := e.Client.ListHypertablePolicies(htId)
+		if err != nil {
+			return "", fmt.Errorf("failed to read policies of %s: %s", htAddress, err)
+		}
+
+		for _, policy := range policies {
+			memberAttr := attribute{"user_email", hclString(policy.Member)}
+			if policy.MemberType == api.MemberTypeGroup {
+				memberAttr = attribute{"group_name", hclString(policy.Member)}
+			}
+
+			typeName, labelName, stateId := "", "", ""
+			attrs := []attribute{{"hypertable_id", htRef}, memberAttr}
+
+			switch policy.Kind {
+			case api.PolicyKindAccessControl:
+				typeName = aclTypeName
+				labelName = htLabel + "_" + policy.Member
+				stateId = e.Client.GetHypertableAccessControlStateId(
+					htId, policy.MemberType, policy.Member,
+				)
+			case api.PolicyKindDataMask:
+				typeName = maskTypeName
+				labelName = htLabel + "_" + policy.Member + "_" + policy.Column
+				stateId = e.Client.GetHypertableDataMaskStateId(
+					htId, policy.MemberType, policy.Member, policy.Column,
+				)
+				attrs = append(attrs,
+					attribute{"masking_option", hclString(policy.MaskingOption)},
+					attribute{"column", hclString(policy.Column)},
+				)
+			case api.PolicyKindRowFilter:
+				typeName = filterTypeName
+				labelName = htLabel + "_" + policy.Member + "_" + policy.Column
+				stateId = e.Client.GetHypertableRowFilterStateId(
+					htId, policy.MemberType, policy.Member, policy.Column,
+				)
+				attrs = append(attrs,
+					attribute{"sql_condition", hclString(policy.FilterExpression)},
+					attribute{"column", hclString(policy.Column)},
+				)
+			}
+
+			label := e.label(typeName, labelName)
+			blocks = append(blocks, renderResource(typeName, label, attrs))
+			e.addImport(typeName+"."+label, stateId)
+		}
+	}
+
+	return strings.Join(blocks, "\n"), nil
+}
+
+func (e *exporter) addImport(address string, stateId string) {
+	e.imports = append(e.imports, fmt.Sprintf(
+		"pct import %s %s", address, shellQuote(stateId),
+	))
+}
+
+func providerTypeName() string {
+	return plugin.NewProvider().Metadata(&schema.ServiceRequest{}).TypeName
+}
+
+func resourceTypeName(newResource func() schema.ResourceService) string {
+	return newResource().Metadata(&schema.ServiceRequest{
+		TypeName: providerTypeName(),
+	}).TypeName
+}
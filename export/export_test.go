@@ -0,0 +1,136 @@
+package export
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+const testDatasources = `{
+	"content": [
+		{
+			"id": "ds1",
+			"name": "Sales \"EU\"",
+			"description": "Costs in ${currency}",
+			"tags": ["sales"],
+			"admins": [],
+			"shortName": "sales",
+			"connectionMetadata": "{\"host\":\"db\",\"password\":\"p\\\\w\"}",
+			"dbConnector": "postgres",
+			"dbSubConnector": "postgres",
+			"dbSubConnectorDisplayName": "PostgreSQL"
+		},
+		{"id": "ds2", "shortName": "gone", "deleted": true}
+	],
+	"number": 0, "size": 1, "totalPages": 1, "totalElements": 2, "last": true
+}`
+
+const testDatasourcesWithoutSecrets = `resource "zipstack_cloud_datasource" "sales" {
+  name                          = "Sales \"EU\""
+  description                   = "Costs in $${currency}"
+  tags                          = ["sales"]
+  admins                        = []
+  short_name                    = "sales"
+  # connection_metadata is not exported, set it before applying
+  db_connector                  = "postgres"
+  db_sub_connector              = "postgres"
+  db_sub_connector_display_name = "PostgreSQL"
+}
+`
+
+const testDatasourcesWithSecrets = `resource "zipstack_cloud_datasource" "sales" {
+  name                          = "Sales \"EU\""
+  description                   = "Costs in $${currency}"
+  tags                          = ["sales"]
+  admins                        = []
+  short_name                    = "sales"
+  connection_metadata           = "{\"host\":\"db\",\"password\":\"p\\\\w\"}"
+  db_connector                  = "postgres"
+  db_sub_connector              = "postgres"
+  db_sub_connector_display_name = "PostgreSQL"
+}
+`
+
+func newTestClient(t *testing.T) *api.Client {
+	t.Helper()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/account/login":
+			http.SetCookie(w, &http.Cookie{Name: "SESSION", Value: "session"})
+			http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: "token"})
+		case "/api/v1/catalog/meshdb/":
+			w.Write([]byte(testDatasources))
+		case "/api/v1/catalog/hypertable/":
+			w.Write([]byte(`{"content": [], "totalPages": 0, "last": true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	c, err := api.NewClient(s.URL, "org", "export@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestExportSecrets(t *testing.T) {
+	tests := []struct {
+		name           string
+		includeSecrets bool
+		want           string
+	}{
+		{"without secrets", false, testDatasourcesWithoutSecrets},
+		{"with secrets", true, testDatasourcesWithSecrets},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := Export(newTestClient(t), Options{
+				Host:             "https://cloud.example.com",
+				OrganisationName: "org",
+				Email:            "export@example.com",
+				OutDir:           dir,
+				IncludeSecrets:   tt.includeSecrets,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := readExported(t, dir, "datasources.hcl"); got != tt.want {
+				t.Errorf("datasources.hcl =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			provider := readExported(t, dir, "provider.hcl")
+			if strings.Contains(provider, "secret") {
+				t.Errorf("provider.hcl contains the login password:\n%s", provider)
+			}
+
+			script := readExported(t, dir, "import.sh")
+			if !strings.Contains(script, "pct import zipstack_cloud_datasource.sales 'ds1'\n") {
+				t.Errorf("import.sh does not import the datasource:\n%s", script)
+			}
+			if strings.Contains(script, "ds2") {
+				t.Errorf("import.sh imports a deleted datasource:\n%s", script)
+			}
+		})
+	}
+}
+
+func readExported(t *testing.T, dir string, name string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
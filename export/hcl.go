@@ -0,0 +1,152 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Key value pair inside a configuration block or object.
+// Value is expected to be already rendered, see the helpers below.
+// An attribute without name is a comment line, see hclComment.
+type attribute struct {
+	Name  string
+	Value string
+}
+
+var nonIdentChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// Render a resource block with the given attributes.
+func renderResource(typeName string, label string, attrs []attribute) string {
+	return fmt.Sprintf(
+		"resource %s %s %s\n",
+		hclString(typeName), hclString(label), renderObject(attrs, 0),
+	)
+}
+
+// Render an object, aligning the "=" of consecutive single line
+// attributes the same way the formatter does.
+func renderObject(attrs []attribute, indent int) string {
+	pad := strings.Repeat("  ", indent+1)
+
+	width := 0
+	for _, attr := range attrs {
+		if !strings.Contains(attr.Value, "\n") && len(attr.Name) > width {
+			width = len(attr.Name)
+		}
+	}
+
+	s := "{\n"
+	for _, attr := range attrs {
+		if attr.Name == "" {
+			s += pad + attr.Value + "\n"
+		} else if strings.Contains(attr.Value, "\n") {
+			s += fmt.Sprintf("%s%s = %s\n", pad, attr.Name, attr.Value)
+		} else {
+			s += fmt.Sprintf("%s%-*s = %s\n", pad, width, attr.Name, attr.Value)
+		}
+	}
+	s += strings.Repeat("  ", indent) + "}"
+
+	return s
+}
+
+// Render a list of objects.
+func renderObjectList(objects [][]attribute, indent int) string {
+	if len(objects) == 0 {
+		return "[]"
+	}
+
+	pad := strings.Repeat("  ", indent+1)
+
+	s := "[\n"
+	for _, obj := range objects {
+		s += pad + renderObject(obj, indent+1) + ",\n"
+	}
+	s += strings.Repeat("  ", indent) + "]"
+
+	return s
+}
+
+// Render a string literal. Multi line strings (e.g. SQL) are
+// rendered as heredocs to keep them reviewable. A heredoc's value ends
+// with the newline before its closing marker, hence only strings which
+// end with a newline are rendered as heredocs, the others would not
+// round-trip byte for byte.
+func hclString(s string) string {
+	escaped := strings.ReplaceAll(s, "${", "$${")
+	escaped = strings.ReplaceAll(escaped, "%{", "%%{")
+
+	if strings.HasSuffix(s, "\n") && !strings.Contains(s, "\r") && !containsLine(s, "EOT") {
+		return "<<EOT\n" + escaped + "EOT"
+	}
+
+	escaped = strings.ReplaceAll(escaped, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, `"`, `\"`)
+	escaped = strings.ReplaceAll(escaped, "\n", `\n`)
+	escaped = strings.ReplaceAll(escaped, "\r", `\r`)
+	escaped = strings.ReplaceAll(escaped, "\t", `\t`)
+
+	return `"` + escaped + `"`
+}
+
+func hclStringList(ss []string) string {
+	items := []string{}
+	for _, s := range ss {
+		items = append(items, hclString(s))
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// Comment line in place of an attribute.
+func hclComment(text string) attribute {
+	return attribute{Value: "# " + text}
+}
+
+func hclBool(b bool) string {
+	return strconv.FormatBool(b)
+}
+
+// Render a reference to an attribute of another resource.
+func hclRef(address string, attr string) string {
+	return address + "." + attr
+}
+
+func containsLine(s string, line string) bool {
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+	return false
+}
+
+// Convert a free form name into a valid, unique block label.
+func (e *exporter) label(typeName string, name string) string {
+	base := nonIdentChars.ReplaceAllString(strings.ToLower(name), "_")
+	base = strings.Trim(base, "_")
+	if base == "" {
+		base = "unnamed"
+	}
+	if base[0] >= '0' && base[0] <= '9' {
+		base = "r_" + base
+	}
+
+	if e.labels[typeName] == nil {
+		e.labels[typeName] = map[string]bool{}
+	}
+
+	label := base
+	for i := 2; e.labels[typeName][label]; i++ {
+		label = fmt.Sprintf("%s_%d", base, i)
+	}
+	e.labels[typeName][label] = true
+
+	return label
+}
+
+// Quote a value for use in a POSIX shell script.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,121 @@
+package export
+
+import (
+	"testing"
+)
+
+func TestHclString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", `""`},
+		{"plain", `"plain"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\path`, `"C:\\path"`},
+		{"${var}", `"$${var}"`},
+		{"%{ if x }", `"%%{ if x }"`},
+		{"$5 and 100%", `"$5 and 100%"`},
+		{"tab\there", `"tab\there"`},
+		{"no trailing\nnewline", `"no trailing\nnewline"`},
+		{"crlf\r\n", `"crlf\r\n"`},
+		{"SELECT *\nFROM t\n", "<<EOT\nSELECT *\nFROM t\nEOT"},
+		{"SELECT \"a\\b\"\n", "<<EOT\nSELECT \"a\\b\"\nEOT"},
+		{"SELECT '${x}'\n", "<<EOT\nSELECT '$${x}'\nEOT"},
+		{"a\nEOT\n", `"a\nEOT\n"`},
+		{"a\n  EOT\n", `"a\n  EOT\n"`},
+		{"\n", "<<EOT\n\nEOT"},
+	}
+
+	for _, tt := range tests {
+		if got := hclString(tt.in); got != tt.want {
+			t.Errorf("hclString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHclStringList(t *testing.T) {
+	if got := hclStringList(nil); got != "[]" {
+		t.Errorf("hclStringList(nil) = %q, want []", got)
+	}
+	if got, want := hclStringList([]string{"a", `b"c`}), `["a", "b\"c"]`; got != want {
+		t.Errorf("hclStringList = %q, want %q", got, want)
+	}
+}
+
+func TestRenderResource(t *testing.T) {
+	attrs := []attribute{
+		{"name", hclString("Orders")},
+		{"short_name", hclString("orders")},
+		hclComment("secret is not exported, set it before applying"),
+		{"tags", hclStringList([]string{"a", "b"})},
+		{"sql_select", hclString("SELECT *\nFROM orders\n")},
+		{"stages", renderObjectList([][]attribute{
+			{{"query", hclString("SELECT 1")}, {"short_name", hclString("s1")}},
+			{{"query", hclString("SELECT 2\n")}, {"depends_on", hclStringList([]string{"s1"})}},
+		}, 1)},
+		{"empty", renderObjectList(nil, 1)},
+		{"status", hclBool(true)},
+		{"hypertable_id", hclRef("zipstack_hypertable_live.orders", "id")},
+	}
+
+	want := `resource "zipstack_hypertable_live" "orders" {
+  name          = "Orders"
+  short_name    = "orders"
+  # secret is not exported, set it before applying
+  tags          = ["a", "b"]
+  sql_select = <<EOT
+SELECT *
+FROM orders
+EOT
+  stages = [
+    {
+      query      = "SELECT 1"
+      short_name = "s1"
+    },
+    {
+      query = <<EOT
+SELECT 2
+EOT
+      depends_on = ["s1"]
+    },
+  ]
+  empty         = []
+  status        = true
+  hypertable_id = zipstack_hypertable_live.orders.id
+}
+`
+	if got := renderResource("zipstack_hypertable_live", "orders", attrs); got != want {
+		t.Errorf("renderResource =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	e := &exporter{labels: map[string]map[string]bool{}}
+
+	tests := []struct {
+		typeName string
+		name     string
+		want     string
+	}{
+		{"a", "Orders", "orders"},
+		{"a", "orders", "orders_2"},
+		{"a", "Orders!", "orders_3"},
+		{"b", "orders", "orders"},
+		{"a", "alice@example.com", "alice_example_com"},
+		{"a", "2024 sales", "r_2024_sales"},
+		{"a", "!!!", "unnamed"},
+	}
+
+	for _, tt := range tests {
+		if got := e.label(tt.typeName, tt.name); got != tt.want {
+			t.Errorf("label(%q, %q) = %q, want %q", tt.typeName, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got, want := shellQuote("v2:ht1:user:it's"), `'v2:ht1:user:it'\''s'`; got != want {
+		t.Errorf("shellQuote = %q, want %q", got, want)
+	}
+}
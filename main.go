@@ -1,9 +1,12 @@
 package main
 
 import (
+	"os"
+
 	"github.com/zipstack/pct-plugin-framework/schema"
 	"github.com/zipstack/pct-plugin-framework/server"
 
+	"github.com/zipstack/pct-provider-zipstack-cloud/export"
 	"github.com/zipstack/pct-provider-zipstack-cloud/plugin"
 )
 
@@ -11,6 +14,11 @@ import (
 var version string
 
 func main() {
+	// Companion commands run standalone, without the plugin server.
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(export.Run(os.Args[2:]))
+	}
+
 	server.Serve(version, plugin.NewProvider, []func() schema.ResourceService{
 		plugin.NewDatasourceResource,
 		plugin.NewHypertableLiveResource,
@@ -18,5 +26,13 @@ func main() {
 		plugin.NewHypertableAccessControlResource,
 		plugin.NewHypertableDataMaskResource,
 		plugin.NewHypertableRowFilterResource,
+		plugin.NewHypertableAccessPolicyResource,
+		plugin.NewHypertableMaskingPolicyResource,
+		plugin.NewHypertableRowFilterPolicyResource,
+
+		plugin.NewDatasourceDataSource,
+		plugin.NewHypertableDataSource,
+		plugin.NewHypertableRunsDataSource,
+		plugin.NewHypertableColumnsDataSource,
 	})
 }
@@ -0,0 +1,586 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron expressions are in the Quartz dialect accepted by the server:
+//
+//	second minute hour day-of-month month day-of-week [year]
+//
+// Exactly one of day-of-month and day-of-week must be "?". Days of the
+// week are numbered 1 (SUN) to 7 (SAT). Besides "*", "?", lists, ranges
+// and increments, the "L", "W" and "#" day modifiers are supported.
+//
+// Fire times are wall clock times in the schedule's location. As in
+// Quartz, times skipped by a daylight saving transition fire shifted
+// forward by the length of the gap, while times repeated by one fire
+// only once.
+type cronSchedule struct {
+	Expression string
+	Location   *time.Location
+
+	fields [7]cronField
+}
+
+const (
+	cronSecond = iota
+	cronMinute
+	cronHour
+	cronDayOfMonth
+	cronMonth
+	cronDayOfWeek
+	cronYear
+)
+
+type cronFieldSpec struct {
+	Name  string
+	Unit  string
+	Min   int
+	Max   int
+	Names []string
+}
+
+var cronFieldSpecs = [7]cronFieldSpec{
+	{"seconds", "second", 0, 59, nil},
+	{"minutes", "minute", 0, 59, nil},
+	{"hours", "hour", 0, 23, nil},
+	{"day-of-month", "day", 1, 31, nil},
+	{"month", "month", 1, 12, []string{
+		"JAN", "FEB", "MAR", "APR", "MAY", "JUN",
+		"JUL", "AUG", "SEP", "OCT", "NOV", "DEC",
+	}},
+	{"day-of-week", "day", 1, 7, []string{
+		"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT",
+	}},
+	{"year", "year", 1970, 2099, nil},
+}
+
+// A single comma separated item of a field.
+type cronItem struct {
+	From int
+	To   int
+	Step int
+	All  bool
+}
+
+type cronField struct {
+	Spec  cronFieldSpec
+	Items []cronItem
+
+	// "?" in day-of-month or day-of-week.
+	NoSpecific bool
+	// Day-of-month: "L" or "L-n", Offset holds n.
+	LastDay bool
+	Offset  int
+	// Day-of-month: "nW" or "LW".
+	NearestWeekday bool
+	// Day-of-week: "nL", last such weekday of the month.
+	LastWeekday bool
+	// Day-of-week: "n#k", k-th such weekday of the month.
+	Nth int
+
+	values map[int]bool
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != 6 && len(parts) != 7 {
+		return nil, fmt.Errorf(
+			"invalid cron expression %q: expected 6 or 7 fields "+
+				"(second minute hour day-of-month month day-of-week [year]), got %d",
+			expr, len(parts),
+		)
+	}
+	if len(parts) == 6 {
+		parts = append(parts, "*")
+	}
+
+	c := &cronSchedule{Expression: expr, Location: time.UTC}
+	for idx, part := range parts {
+		field, err := parseCronField(strings.ToUpper(part), cronFieldSpecs[idx], idx)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %s", expr, err)
+		}
+		c.fields[idx] = field
+	}
+
+	dom, dow := c.fields[cronDayOfMonth], c.fields[cronDayOfWeek]
+	if dom.NoSpecific == dow.NoSpecific {
+		return nil, fmt.Errorf(
+			"invalid cron expression %q: exactly one of day-of-month "+
+				"and day-of-week must be \"?\"", expr,
+		)
+	}
+
+	return c, nil
+}
+
+func parseCronField(s string, spec cronFieldSpec, idx int) (cronField, error) {
+	f := cronField{Spec: spec, values: map[int]bool{}}
+
+	if s == "?" {
+		if idx != cronDayOfMonth && idx != cronDayOfWeek {
+			return f, fmt.Errorf("\"?\" is only allowed in day-of-month and day-of-week")
+		}
+		f.NoSpecific = true
+		return f, nil
+	}
+
+	// Day modifiers, which cannot be combined with lists.
+	if idx == cronDayOfMonth && strings.HasPrefix(s, "L") {
+		f.LastDay = true
+		rest := strings.TrimPrefix(s, "L")
+		switch {
+		case rest == "":
+		case rest == "W":
+			f.NearestWeekday = true
+		case strings.HasPrefix(rest, "-"):
+			n, err := strconv.Atoi(rest[1:])
+			if err != nil || n < 0 || n > 30 {
+				return f, fmt.Errorf("invalid day-of-month %q", s)
+			}
+			f.Offset = n
+		default:
+			return f, fmt.Errorf("invalid day-of-month %q", s)
+		}
+		return f, nil
+	}
+	if idx == cronDayOfMonth && strings.HasSuffix(s, "W") {
+		v, err := spec.value(strings.TrimSuffix(s, "W"))
+		if err != nil {
+			return f, err
+		}
+		f.NearestWeekday = true
+		f.Items = []cronItem{{From: v, To: v, Step: 1}}
+		return f, nil
+	}
+	if idx == cronDayOfWeek && s == "L" {
+		s = "7"
+	}
+	if idx == cronDayOfWeek && strings.HasSuffix(s, "L") {
+		v, err := spec.value(strings.TrimSuffix(s, "L"))
+		if err != nil {
+			return f, err
+		}
+		f.LastWeekday = true
+		f.Items = []cronItem{{From: v, To: v, Step: 1}}
+		return f, nil
+	}
+	if idx == cronDayOfWeek && strings.Contains(s, "#") {
+		parts := strings.SplitN(s, "#", 2)
+		v, err := spec.value(parts[0])
+		if err != nil {
+			return f, err
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 1 || n > 5 {
+			return f, fmt.Errorf("invalid day-of-week %q: occurrence must be 1 to 5", s)
+		}
+		f.Nth = n
+		f.Items = []cronItem{{From: v, To: v, Step: 1}}
+		return f, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		item, err := spec.item(part)
+		if err != nil {
+			return f, err
+		}
+		f.Items = append(f.Items, item)
+
+		for v := item.From; v <= item.To; v += item.Step {
+			f.values[v] = true
+		}
+	}
+
+	return f, nil
+}
+
+func (spec cronFieldSpec) item(s string) (cronItem, error) {
+	item := cronItem{Step: 1}
+
+	rangePart := s
+	if strings.Contains(s, "/") {
+		parts := strings.SplitN(s, "/", 2)
+		rangePart = parts[0]
+
+		step, err := strconv.Atoi(parts[1])
+		if err != nil || step < 1 || step > spec.Max {
+			return item, fmt.Errorf("invalid increment %q in %s", parts[1], spec.Name)
+		}
+		item.Step = step
+	}
+
+	switch {
+	case rangePart == "*":
+		item.All = item.Step == 1
+		item.From, item.To = spec.Min, spec.Max
+	case strings.Contains(rangePart, "-"):
+		parts := strings.SplitN(rangePart, "-", 2)
+		from, err := spec.value(parts[0])
+		if err != nil {
+			return item, err
+		}
+		to, err := spec.value(parts[1])
+		if err != nil {
+			return item, err
+		}
+		if from > to {
+			return item, fmt.Errorf("invalid range %q in %s", rangePart, spec.Name)
+		}
+		item.From, item.To = from, to
+	default:
+		v, err := spec.value(rangePart)
+		if err != nil {
+			return item, err
+		}
+		item.From, item.To = v, v
+		// "a/n" starts at a and repeats till the end of the range.
+		if strings.Contains(s, "/") {
+			item.To = spec.Max
+		}
+	}
+
+	return item, nil
+}
+
+func (spec cronFieldSpec) value(s string) (int, error) {
+	for idx, name := range spec.Names {
+		if s == name {
+			return spec.Min + idx, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < spec.Min || v > spec.Max {
+		return 0, fmt.Errorf(
+			"invalid %s %q: must be %d to %d", spec.Name, s, spec.Min, spec.Max,
+		)
+	}
+	return v, nil
+}
+
+func (f cronField) matches(v int) bool {
+	return f.values[v]
+}
+
+// Whether the day of the given time is selected by the day-of-month
+// and day-of-week fields.
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	dom, dow := c.fields[cronDayOfMonth], c.fields[cronDayOfWeek]
+	lastDay := daysIn(t.Month(), t.Year())
+
+	if !dom.NoSpecific {
+		switch {
+		case dom.LastDay && dom.NearestWeekday:
+			return t.Day() == nearestWeekday(t, lastDay)
+		case dom.LastDay:
+			return t.Day() == lastDay-dom.Offset
+		case dom.NearestWeekday:
+			return t.Day() == nearestWeekday(t, dom.Items[0].From)
+		default:
+			return dom.matches(t.Day())
+		}
+	}
+
+	weekday := int(t.Weekday()) + 1
+	switch {
+	case dow.LastWeekday:
+		return weekday == dow.Items[0].From && t.Day()+7 > lastDay
+	case dow.Nth > 0:
+		return weekday == dow.Items[0].From && (t.Day()-1)/7+1 == dow.Nth
+	default:
+		return dow.matches(weekday)
+	}
+}
+
+// Weekday nearest to the given day of the month of t, without
+// crossing into the adjacent months.
+func nearestWeekday(t time.Time, day int) int {
+	lastDay := daysIn(t.Month(), t.Year())
+	if day > lastDay {
+		return -1
+	}
+
+	d := time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, time.UTC)
+	switch d.Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == lastDay {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
+
+func daysIn(m time.Month, year int) int {
+	return time.Date(year, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// Next returns the first fire time strictly after the given time, in
+// the schedule's location. The zero time is returned if the schedule
+// never fires again.
+//
+// Like Quartz, fire times are searched on the wall clock. Times
+// skipped by a daylight saving transition fire shifted forward by the
+// length of the gap, e.g. 02:30 fires at 03:30, and times repeated by
+// a transition fire once, at their first occurrence. A shifted time
+// falling onto a fire time of its own fires once.
+func (c *cronSchedule) Next(after time.Time) time.Time {
+	loc := c.Location
+	after = after.In(loc)
+	w := wallClock(after).Add(time.Second)
+
+	for i := 0; i < 100000; i++ {
+		if w.Year() > cronFieldSpecs[cronYear].Max {
+			return time.Time{}
+		}
+
+		switch {
+		case !c.fields[cronYear].matches(w.Year()):
+			w = time.Date(w.Year()+1, 1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.fields[cronMonth].matches(int(w.Month())):
+			w = time.Date(w.Year(), w.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.matchesDay(w):
+			w = time.Date(w.Year(), w.Month(), w.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !c.fields[cronHour].matches(w.Hour()):
+			w = time.Date(w.Year(), w.Month(), w.Day(), w.Hour()+1, 0, 0, 0, time.UTC)
+		case !c.fields[cronMinute].matches(w.Minute()):
+			w = time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute()+1, 0, 0, time.UTC)
+		case !c.fields[cronSecond].matches(w.Second()):
+			w = w.Add(time.Second)
+		default:
+			t := resolveWallClock(w, loc, after)
+			if !t.IsZero() {
+				return t
+			}
+			w = w.Add(time.Second)
+		}
+	}
+
+	return time.Time{}
+}
+
+// Wall clock reading of t, comparable across UTC offsets.
+func wallClock(t time.Time) time.Time {
+	return time.Date(
+		t.Year(), t.Month(), t.Day(),
+		t.Hour(), t.Minute(), t.Second(), 0, time.UTC,
+	)
+}
+
+// First instant after the given time at which the clocks of loc read
+// the wall clock time w, the zero time if there is none. Skipped wall
+// clock times are shifted forward by the length of the gap.
+func resolveWallClock(w time.Time, loc *time.Location, after time.Time) time.Time {
+	// UTC offsets before and after a transition around w. The larger
+	// offset gives the earlier instant.
+	_, before := w.Add(-24 * time.Hour).In(loc).Zone()
+	_, since := w.Add(24 * time.Hour).In(loc).Zone()
+	offsets := []int{before, since}
+	if before < since {
+		offsets = []int{since, before}
+	}
+
+	valid := false
+	for _, offset := range offsets {
+		t := w.Add(-time.Duration(offset) * time.Second).In(loc)
+		if !wallClock(t).Equal(w) {
+			continue
+		}
+		valid = true
+		if t.After(after) {
+			return t
+		}
+	}
+	if valid {
+		return time.Time{}
+	}
+
+	// In the gap of a transition, read with the offset from before.
+	t := w.Add(-time.Duration(before) * time.Second).In(loc)
+	if t.After(after) {
+		return t
+	}
+	return time.Time{}
+}
+
+// NextN returns up to n fire times after the given time.
+func (c *cronSchedule) NextN(after time.Time, n int) []time.Time {
+	times := []time.Time{}
+	t := after
+	for len(times) < n {
+		t = c.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// MinInterval returns the shortest gap between consecutive fire times,
+// sampled over the upcoming runs. Zero is returned for schedules which
+// fire fewer than twice.
+func (c *cronSchedule) MinInterval(after time.Time) time.Duration {
+	times := c.NextN(after, 100)
+
+	var min time.Duration
+	for idx := 1; idx < len(times); idx++ {
+		gap := times[idx].Sub(times[idx-1])
+		if min == 0 || gap < min {
+			min = gap
+		}
+	}
+	return min
+}
+
+// Describe returns a human readable description of the schedule,
+// e.g. "At 02:30, Monday through Friday". Wildcard fields implied by a
+// finer field are left out, e.g. "Every 10 seconds" rather than "Every
+// 10 seconds, every minute, every hour, every day".
+func (c *cronSchedule) Describe() string {
+	sec, min, hour := c.fields[cronSecond], c.fields[cronMinute], c.fields[cronHour]
+	onTheMinute := sec.single() && sec.Items[0].From == 0
+
+	parts := []string{}
+	if sec.single() && min.single() && hour.single() {
+		at := fmt.Sprintf("At %02d:%02d", hour.Items[0].From, min.Items[0].From)
+		if sec.Items[0].From != 0 {
+			at += fmt.Sprintf(":%02d", sec.Items[0].From)
+		}
+		parts = append(parts, at)
+	} else if onTheMinute && min.single() && hour.all() {
+		parts = append(parts, fmt.Sprintf("At minute %d of every hour", min.Items[0].From))
+	} else {
+		if !onTheMinute {
+			parts = append(parts, sec.describe())
+		}
+		// Every minute is implied unless the seconds are single.
+		if !min.all() || sec.single() {
+			parts = append(parts, min.describe())
+		}
+		if !hour.all() {
+			parts = append(parts, hour.describe())
+		}
+	}
+
+	if days := c.describeDays(); days != "" {
+		parts = append(parts, days)
+	}
+
+	if !c.fields[cronMonth].all() {
+		parts = append(parts, "in "+c.fields[cronMonth].describeNames())
+	}
+	if !c.fields[cronYear].all() {
+		parts = append(parts, "in "+c.fields[cronYear].describeNames())
+	}
+
+	s := strings.Join(parts, ", ")
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// Describe the day fields, empty for every day.
+func (c *cronSchedule) describeDays() string {
+	dom, dow := c.fields[cronDayOfMonth], c.fields[cronDayOfWeek]
+
+	if !dom.NoSpecific {
+		switch {
+		case dom.LastDay && dom.NearestWeekday:
+			return "on the last weekday of the month"
+		case dom.LastDay && dom.Offset > 0:
+			return fmt.Sprintf("%d days before the last day of the month", dom.Offset)
+		case dom.LastDay:
+			return "on the last day of the month"
+		case dom.NearestWeekday:
+			return fmt.Sprintf("on the weekday nearest to day %d of the month", dom.Items[0].From)
+		case dom.all():
+			return ""
+		case len(dom.Items) == 1 && dom.Items[0].Step > 1:
+			return dom.describeNames() + " of the month"
+		default:
+			return "on " + dom.describeNames() + " of the month"
+		}
+	}
+
+	switch {
+	case dow.LastWeekday:
+		return "on the last " + dow.describeNames() + " of the month"
+	case dow.Nth > 0:
+		ordinals := []string{"", "first", "second", "third", "fourth", "fifth"}
+		return fmt.Sprintf("on the %s %s of the month", ordinals[dow.Nth], dow.describeNames())
+	case dow.all():
+		return ""
+	default:
+		return dow.describeNames()
+	}
+}
+
+func (f cronField) all() bool {
+	return len(f.Items) == 1 && f.Items[0].All
+}
+
+func (f cronField) single() bool {
+	return len(f.Items) == 1 && f.Items[0].From == f.Items[0].To
+}
+
+// Describe a time field, e.g. "every 15 minutes".
+func (f cronField) describe() string {
+	if f.all() {
+		return "every " + f.Spec.Unit
+	}
+	if f.single() {
+		return fmt.Sprintf("at %s %d", f.Spec.Unit, f.Items[0].From)
+	}
+	if len(f.Items) == 1 && f.Items[0].Step > 1 {
+		return f.describeNames()
+	}
+	return fmt.Sprintf("at %ss %s", f.Spec.Unit, f.describeNames())
+}
+
+// Describe the field values by name where available,
+// e.g. "Monday through Friday".
+func (f cronField) describeNames() string {
+	name := func(v int) string {
+		switch f.Spec.Name {
+		case "month":
+			return time.Month(v).String()
+		case "day-of-week":
+			return time.Weekday(v - 1).String()
+		case "day-of-month":
+			return "day " + strconv.Itoa(v)
+		}
+		return strconv.Itoa(v)
+	}
+
+	items := []string{}
+	for _, item := range f.Items {
+		switch {
+		case item.From == item.To:
+			items = append(items, name(item.From))
+		case item.Step == 1:
+			items = append(items, name(item.From)+" through "+name(item.To))
+		case item.From == f.Spec.Min && item.To == f.Spec.Max:
+			items = append(items, fmt.Sprintf("every %d %ss", item.Step, f.Spec.Unit))
+		default:
+			items = append(items, fmt.Sprintf(
+				"every %d %ss from %s through %s",
+				item.Step, f.Spec.Unit, name(item.From), name(item.To),
+			))
+		}
+	}
+
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
@@ -0,0 +1,296 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronDescribe(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"0 30 2 * * ?", "At 02:30"},
+		{"15 30 2 * * ?", "At 02:30:15"},
+		{"0 30 2 ? * MON-FRI", "At 02:30, Monday through Friday"},
+		{"0 5 * * * ?", "At minute 5 of every hour"},
+		{"* * * * * ?", "Every second"},
+		{"*/10 * * * * ?", "Every 10 seconds"},
+		{"0 * * * * ?", "Every minute"},
+		{"30 * * * * ?", "At second 30, every minute"},
+		{"0 */15 * * * ?", "Every 15 minutes"},
+		{"0 */15 8-18 * * ?", "Every 15 minutes, at hours 8 through 18"},
+		{"0 0 */2 * * ?", "At minute 0, every 2 hours"},
+		{"0 0 6 ? * */2", "At 06:00, every 2 days"},
+		{"0 0 6 ? * 2/2", "At 06:00, every 2 days from Monday through Saturday"},
+		{"0 0 6 */2 * ?", "At 06:00, every 2 days of the month"},
+		{"0 0 6 1,15 * ?", "At 06:00, on day 1 and day 15 of the month"},
+		{"0 0 6 L * ?", "At 06:00, on the last day of the month"},
+		{"0 0 6 ? * 6#3", "At 06:00, on the third Friday of the month"},
+		{"0 0 6 1 JAN ? 2030", "At 06:00, on day 1 of the month, in January, in 2030"},
+	}
+
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q): %s", tt.expr, err)
+			continue
+		}
+		if got := c.Describe(); got != tt.want {
+			t.Errorf("Describe(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextN(t *testing.T) {
+	berlin, err := loadTimezone("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lordHowe, err := loadTimezone("Australia/Lord_Howe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		expr  string
+		loc   *time.Location
+		after string
+		want  []string
+	}{
+		{
+			// 2024-03-31 02:00 CET jumps to 03:00 CEST.
+			name:  "spring forward shifts the missing time",
+			expr:  "0 30 2 * * ?",
+			loc:   berlin,
+			after: "2024-03-30T12:00:00+01:00",
+			want: []string{
+				"2024-03-31T03:30:00+02:00",
+				"2024-04-01T02:30:00+02:00",
+			},
+		},
+		{
+			// 02:00 is shifted onto 03:00, which fires once.
+			name:  "spring forward hourly",
+			expr:  "0 0 * * * ?",
+			loc:   berlin,
+			after: "2024-03-31T00:30:00+01:00",
+			want: []string{
+				"2024-03-31T01:00:00+01:00",
+				"2024-03-31T03:00:00+02:00",
+				"2024-03-31T04:00:00+02:00",
+			},
+		},
+		{
+			name:  "spring forward within the missing hour",
+			expr:  "0 */20 * * * ?",
+			loc:   berlin,
+			after: "2024-03-31T01:30:00+01:00",
+			want: []string{
+				"2024-03-31T01:40:00+01:00",
+				"2024-03-31T03:00:00+02:00",
+				"2024-03-31T03:20:00+02:00",
+			},
+		},
+		{
+			name:  "spring forward shifts onto a later fire time",
+			expr:  "0 30 2,3 * * ?",
+			loc:   berlin,
+			after: "2024-03-31T00:00:00+01:00",
+			want: []string{
+				"2024-03-31T03:30:00+02:00",
+				"2024-04-01T02:30:00+02:00",
+			},
+		},
+		{
+			// 2024-10-06 02:00 jumps to 02:30 on Lord Howe Island.
+			name:  "spring forward by half an hour",
+			expr:  "0 15 2 * * ?",
+			loc:   lordHowe,
+			after: "2024-10-05T12:00:00+10:30",
+			want: []string{
+				"2024-10-06T02:45:00+11:00",
+				"2024-10-07T02:15:00+11:00",
+			},
+		},
+		{
+			// 2024-10-27 03:00 CEST falls back to 02:00 CET.
+			name:  "fall back fires the repeated time once",
+			expr:  "0 30 2 * * ?",
+			loc:   berlin,
+			after: "2024-10-26T12:00:00+02:00",
+			want: []string{
+				"2024-10-27T02:30:00+02:00",
+				"2024-10-28T02:30:00+01:00",
+			},
+		},
+		{
+			name:  "fall back hourly",
+			expr:  "0 0 * * * ?",
+			loc:   berlin,
+			after: "2024-10-27T00:30:00+02:00",
+			want: []string{
+				"2024-10-27T01:00:00+02:00",
+				"2024-10-27T02:00:00+02:00",
+				"2024-10-27T03:00:00+01:00",
+			},
+		},
+		{
+			name:  "fall back within the repeated hour",
+			expr:  "0 */20 * * * ?",
+			loc:   berlin,
+			after: "2024-10-27T02:30:00+02:00",
+			want: []string{
+				"2024-10-27T02:40:00+02:00",
+				"2024-10-27T03:00:00+01:00",
+				"2024-10-27T03:20:00+01:00",
+			},
+		},
+		{
+			name:  "fall back after the first occurrence",
+			expr:  "0 */20 * * * ?",
+			loc:   berlin,
+			after: "2024-10-27T02:10:00+01:00",
+			want: []string{
+				"2024-10-27T02:20:00+01:00",
+				"2024-10-27T02:40:00+01:00",
+				"2024-10-27T03:00:00+01:00",
+			},
+		},
+		{
+			name:  "fall back daily after the first occurrence",
+			expr:  "0 30 2 * * ?",
+			loc:   berlin,
+			after: "2024-10-27T02:30:00+02:00",
+			want: []string{
+				"2024-10-28T02:30:00+01:00",
+			},
+		},
+		{
+			name:  "step",
+			expr:  "0 5/20 * * * ?",
+			loc:   time.UTC,
+			after: "2024-01-01T00:00:00Z",
+			want: []string{
+				"2024-01-01T00:05:00Z",
+				"2024-01-01T00:25:00Z",
+				"2024-01-01T00:45:00Z",
+				"2024-01-01T01:05:00Z",
+			},
+		},
+		{
+			name:  "range",
+			expr:  "0 0 22-23 * * ?",
+			loc:   time.UTC,
+			after: "2024-01-01T00:00:00Z",
+			want: []string{
+				"2024-01-01T22:00:00Z",
+				"2024-01-01T23:00:00Z",
+				"2024-01-02T22:00:00Z",
+			},
+		},
+		{
+			name:  "list",
+			expr:  "0 0 9 ? * MON,WED,FRI",
+			loc:   time.UTC,
+			after: "2024-01-01T12:00:00Z",
+			want: []string{
+				"2024-01-03T09:00:00Z",
+				"2024-01-05T09:00:00Z",
+				"2024-01-08T09:00:00Z",
+			},
+		},
+		{
+			name:  "stepped range in a list",
+			expr:  "0 0 8-12/2,18 * * ?",
+			loc:   time.UTC,
+			after: "2024-01-01T00:00:00Z",
+			want: []string{
+				"2024-01-01T08:00:00Z",
+				"2024-01-01T10:00:00Z",
+				"2024-01-01T12:00:00Z",
+				"2024-01-01T18:00:00Z",
+			},
+		},
+		{
+			name:  "last year",
+			expr:  "0 0 0 1 1 ? 2025",
+			loc:   time.UTC,
+			after: "2024-01-01T00:00:00Z",
+			want:  []string{"2025-01-01T00:00:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron(%q): %s", tt.expr, err)
+			}
+			c.Location = tt.loc
+
+			after, err := time.Parse(time.RFC3339, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, next := range c.NextN(after, len(tt.want)+1) {
+				got = append(got, next.Format(time.RFC3339))
+			}
+			if len(got) > len(tt.want) {
+				got = got[:len(tt.want)]
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("NextN(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"0 0 * * *",
+		"0 0 0 * * ? 2024 1",
+		"0 0 0 * * *",
+		"0 0 0 ? * ?",
+		"60 0 0 * * ?",
+		"0 60 0 * * ?",
+		"0 0 24 * * ?",
+		"0 0 0 32 * ?",
+		"0 0 0 * 13 ?",
+		"0 0 0 ? * 8",
+		"0 0 0 * * ? 1969",
+		"0 ? 0 * * ?",
+		"0 0 0-25 * * ?",
+		"0 0 10-2 * * ?",
+		"0 */0 * * * ?",
+		"0 */x * * * ?",
+		"0 0 0 ? * MON#6",
+		"0 0 0 L-31 * ?",
+		"0 0 0 ? * FOO",
+		"a b c d e f",
+	}
+
+	for _, expr := range tests {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestLoadTimezone(t *testing.T) {
+	for _, name := range []string{"", "UTC", "Europe/Berlin", "America/New_York"} {
+		if _, err := loadTimezone(name); err != nil {
+			t.Errorf("loadTimezone(%q): %s", name, err)
+		}
+	}
+	for _, name := range []string{"Local", "Europe/Nowhere", "CEST+2"} {
+		if _, err := loadTimezone(name); err == nil {
+			t.Errorf("loadTimezone(%q) succeeded, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zipstack/pct-plugin-framework/fwhelpers"
+	"github.com/zipstack/pct-plugin-framework/schema"
+	"golang.org/x/exp/slices"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Data source implementation.
+// The plugin framework only knows about resources, hence data sources
+// are resources which never create, modify or delete remote objects.
+type datasourceDataSource struct {
+	Client *api.Client
+}
+
+// Same as datasourceResourceModel, without the connection metadata.
+type datasourceDataSourceModel struct {
+	Id                        string   `pctsdk:"id"`
+	Name                      string   `pctsdk:"name"`
+	Description               string   `pctsdk:"description"`
+	Tags                      []string `pctsdk:"tags"`
+	DatasourceTags            []string `pctsdk:"datasource_tags"`
+	Admins                    []string `pctsdk:"admins"`
+	ShortName                 string   `pctsdk:"short_name"`
+	DbConnector               string   `pctsdk:"db_connector"`
+	DbSubConnector            string   `pctsdk:"db_sub_connector"`
+	DbSubConnectorDisplayName string   `pctsdk:"db_sub_connector_display_name"`
+	LastModifiedDate          string   `pctsdk:"last_modified_date"`
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ schema.ResourceService = &datasourceDataSource{}
+)
+
+// Helper function to return a data source service instance.
+func NewDatasourceDataSource() schema.ResourceService {
+	return &datasourceDataSource{}
+}
+
+// Metadata returns the data source type name.
+// It is always provider name + "_data_" + resource type name.
+func (r *datasourceDataSource) Metadata(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{
+		TypeName: req.TypeName + "_data_datasource",
+	}
+}
+
+// Configure adds the provider configured client to the data source.
+func (r *datasourceDataSource) Configure(req *schema.ServiceRequest) *schema.ServiceResponse {
+	if req.ResourceData == "" {
+		return schema.ErrorResponse(fmt.Errorf("no data provided to configure resource"))
+	}
+
+	var creds map[string]string
+	err := fwhelpers.Decode(req.ResourceData, &creds)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	client, err := api.NewClient(
+		creds["host"], creds["organisationname"],
+		creds["email"], creds["password"],
+	)
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("malformed data provided to configure resource"))
+	}
+
+	r.Client = client
+
+	return &schema.ServiceResponse{}
+}
+
+// Schema defines the schema for the data source.
+func (r *datasourceDataSource) Schema() *schema.ServiceResponse {
+	s := &schema.Schema{
+		Description: "Read-only datasource lookup for Zipstack Cloud. " +
+			"Exactly one of id, short_name or tags must be set.",
+		Attributes: map[string]schema.Attribute{
+			"id": &schema.StringAttribute{
+				Description: "ID",
+				Optional:    true,
+				Computed:    true,
+			},
+			"short_name": &schema.StringAttribute{
+				Description: "Short Name",
+				Optional:    true,
+				Computed:    true,
+			},
+			"tags": &schema.ListAttribute{
+				Description: "Tags to look up by, all of which must be present on the datasource",
+				Optional:    true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "Tag",
+					Required:    true,
+				},
+			},
+			"datasource_tags": &schema.ListAttribute{
+				Description: "Tags of the datasource",
+				Computed:    true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "Tag",
+					Computed:    true,
+				},
+			},
+			"name": &schema.StringAttribute{
+				Description: "Name",
+				Computed:    true,
+			},
+			"description": &schema.StringAttribute{
+				Description: "Description",
+				Computed:    true,
+			},
+			"admins": &schema.ListAttribute{
+				Description: "Admins",
+				Computed:    true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "Admin",
+					Computed:    true,
+				},
+			},
+			"db_connector": &schema.StringAttribute{
+				Description: "DB Connector",
+				Computed:    true,
+			},
+			"db_sub_connector": &schema.StringAttribute{
+				Description: "DB Sub Connector",
+				Computed:    true,
+			},
+			"db_sub_connector_display_name": &schema.StringAttribute{
+				Description: "DB Sub Connector Display Name",
+				Computed:    true,
+			},
+			"last_modified_date": &schema.StringAttribute{
+				Description: "Last Modified Date",
+				Computed:    true,
+			},
+		},
+	}
+
+	sEnc, err := fwhelpers.Encode(s)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		SchemaContents: sEnc,
+	}
+}
+
+// Create looks up the datasource, nothing is created.
+func (r *datasourceDataSource) Create(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var plan datasourceDataSourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	err = validateLookupCriteria(plan.Id, plan.ShortName, plan.Tags)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.lookup(plan, plan.Tags)
+}
+
+// Read refreshes the datasource looked up earlier.
+func (r *datasourceDataSource) Read(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var state datasourceDataSourceModel
+	err := fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	if req.StateID == "" {
+		// No previous state exists.
+		return &schema.ServiceResponse{StateContents: req.StateContents}
+	}
+
+	return r.lookup(datasourceDataSourceModel{Id: req.StateID}, state.Tags)
+}
+
+// Update looks up the datasource again with the changed criteria.
+func (r *datasourceDataSource) Update(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var plan datasourceDataSourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	var state datasourceDataSourceModel
+	err = fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	// id and short_name are computed too, the plan carries over the
+	// values of the previous lookup unless they are configured. Only
+	// changed values count as criteria, the previous lookup stands when
+	// none changed.
+	criteria := datasourceDataSourceModel{Tags: plan.Tags}
+	if plan.Id != state.Id {
+		criteria.Id = plan.Id
+	}
+	if plan.ShortName != state.ShortName {
+		criteria.ShortName = plan.ShortName
+	}
+	if criteria.Id == "" && criteria.ShortName == "" && len(criteria.Tags) == 0 {
+		criteria.Id = req.StateID
+	}
+	err = validateLookupCriteria(criteria.Id, criteria.ShortName, criteria.Tags)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.lookup(criteria, plan.Tags)
+}
+
+// Delete only removes the state, the datasource is left untouched.
+func (r *datasourceDataSource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{}
+}
+
+// Look up the datasource by the criteria, the configured tags are kept in
+// state as they are.
+func (r *datasourceDataSource) lookup(criteria datasourceDataSourceModel, tags []string) *schema.ServiceResponse {
+	datasource := api.Datasource{}
+	var err error
+
+	switch {
+	case criteria.Id != "":
+		datasource, err = r.Client.ReadDatasource(criteria.Id)
+		if err == nil && datasource.Deleted {
+			err = api.ErrNotFound
+		}
+	case criteria.ShortName != "":
+		datasource, err = r.Client.FindDatasourceByShortName(criteria.ShortName)
+	case len(criteria.Tags) > 0:
+		datasource, err = r.findByTags(criteria.Tags)
+	}
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("datasource lookup failed: %s", err))
+	}
+
+	state := datasourceDataSourceModel{}
+	state.Id = datasource.Id
+	state.Name = datasource.Name
+	state.Description = datasource.Description
+	state.Tags = tags
+	state.DatasourceTags = datasource.Tags
+	state.Admins = datasource.Admins
+	state.ShortName = datasource.ShortName
+	state.DbConnector = datasource.DbConnector
+	state.DbSubConnector = datasource.DbSubConnector
+	state.DbSubConnectorDisplayName = datasource.DbSubConnectorDisplayName
+	state.LastModifiedDate = datasource.LastModifiedDate
+
+	stateEnc, err := fwhelpers.PackModel(nil, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	t := strings.Split(datasource.LastModifiedDate, ".")[0] + "Z"
+	tp, err := time.Parse(time.RFC3339, t)
+	if err != nil {
+		tp = time.Now()
+	}
+
+	return &schema.ServiceResponse{
+		StateID:          datasource.Id,
+		StateContents:    stateEnc,
+		StateLastUpdated: tp.Format(time.RFC850),
+	}
+}
+
+// Exactly one datasource must carry all of the tags.
+func (r *datasourceDataSource) findByTags(tags []string) (api.Datasource, error) {
+	matches := []api.Datasource{}
+
+	it := r.Client.IterateDatasources(api.ListOptions{Tag: tags[0]})
+	for it.Next() {
+		datasource := it.Datasource()
+		if !datasource.Deleted && hasAllTags(datasource.Tags, tags) {
+			matches = append(matches, datasource)
+		}
+	}
+	if it.Err() != nil {
+		return api.Datasource{}, it.Err()
+	}
+
+	if len(matches) == 0 {
+		return api.Datasource{}, api.ErrNotFound
+	}
+	if len(matches) > 1 {
+		return api.Datasource{}, fmt.Errorf(
+			"%d datasources match tags %v", len(matches), tags,
+		)
+	}
+	return matches[0], nil
+}
+
+// Exactly one of id, short_name or tags must be set.
+func validateLookupCriteria(id string, shortName string, tags []string) error {
+	set := []string{}
+	if id != "" {
+		set = append(set, "id")
+	}
+	if shortName != "" {
+		set = append(set, "short_name")
+	}
+	if len(tags) > 0 {
+		set = append(set, "tags")
+	}
+
+	if len(set) == 0 {
+		return fmt.Errorf("exactly one of id, short_name or tags must be set")
+	}
+	if len(set) > 1 {
+		return fmt.Errorf(
+			"exactly one of id, short_name or tags must be set, got %s",
+			strings.Join(set, ", "),
+		)
+	}
+	return nil
+}
+
+func hasAllTags(tags []string, required []string) bool {
+	for _, req := range required {
+		if !slices.Contains(tags, req) {
+			return false
+		}
+	}
+	return true
+}
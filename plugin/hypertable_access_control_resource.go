@@ -1,8 +1,8 @@
 package plugin
 
 import (
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/zipstack/pct-plugin-framework/fwhelpers"
@@ -21,6 +21,7 @@ type hypertableAccessControlResourceModel struct {
 	HypertableId string `pctsdk:"hypertable_id"`
 	UserEmail    string `pctsdk:"user_email"`
 	GroupName    string `pctsdk:"group_name"`
+	MemberType   string `pctsdk:"member_type"`
 }
 
 // Ensure the implementation satisfies the expected interfaces.
@@ -89,6 +90,10 @@ func (r *hypertableAccessControlResource) Schema() *schema.ServiceResponse {
 				Required:    true,
 				Optional:    true,
 			},
+			"member_type": &schema.StringAttribute{
+				Description: "Member type, user or group",
+				Computed:    true,
+			},
 		},
 	}
 
@@ -113,10 +118,14 @@ func (r *hypertableAccessControlResource) Create(req *schema.ServiceRequest) *sc
 		return schema.ErrorResponse(err)
 	}
 
-	if plan.UserEmail != "" && plan.GroupName != "" {
-		return schema.ErrorResponse(fmt.Errorf(
-			"both user email and group name cannot be provided",
-		))
+	return r.create(plan)
+}
+
+// Create or update the policy of the plan.
+func (r *hypertableAccessControlResource) create(plan hypertableAccessControlResourceModel) *schema.ServiceResponse {
+	memberType, member, err := policyMember(plan.UserEmail, plan.GroupName)
+	if err != nil {
+		return schema.ErrorResponse(err)
 	}
 
 	// Generate API request body from plan
@@ -140,52 +149,23 @@ func (r *hypertableAccessControlResource) Create(req *schema.ServiceRequest) *sc
 	state := hypertableAccessControlResourceModel{}
 
 	// Query using created state.
-	htACL, err := r.Client.ReadHypertableAccessControl(plan.HypertableId)
-	if err != nil {
-		return schema.ErrorResponse(err)
-	}
-
-	// For a given hypertable, the list of users or groups
-	// is returned. Hence, we need to retrieve the matching
-	// hypertable ID and user or group combination.
-	found := false
-	if len(plan.UserEmail) > 0 {
-		for _, policy := range htACL.Users {
-			if policy.Member == plan.UserEmail {
-				state.PolicyId = policy.PolicyId
-				state.UserEmail = policy.Member
-				found = true
-				break
-			}
-		}
-	}
-	if !found && len(plan.GroupName) > 0 {
-		for _, policy := range htACL.Groups {
-			if policy.Member == plan.GroupName {
-				state.PolicyId = policy.PolicyId
-				state.GroupName = policy.Member
-				found = true
-				break
-			}
-		}
-	}
-
-	if !found {
+	policy, err := r.Client.FindHypertablePolicy(api.PolicyKindAccessControl, api.PolicyStateId{
+		HypertableId: plan.HypertableId, MemberType: memberType,
+		Member: member,
+	})
+	if errors.Is(err, api.ErrNotFound) {
 		return schema.ErrorResponse(fmt.Errorf("failed to create access control"))
+	} else if err != nil {
+		return schema.ErrorResponse(err)
 	}
-
-	state.HypertableId = htACL.HypertableId
+	r.setState(&state, policy)
 
 	// Set refreshed state
-	userOrGroup := plan.UserEmail
-	if len(userOrGroup) == 0 {
-		userOrGroup = plan.GroupName
-	}
 	// We create a resource for each user or group, but
 	// the retrieval from provider is via hypertable ID.
 	// Hence state ID needs to be a combination of both.
 	stateId := r.Client.GetHypertableAccessControlStateId(
-		plan.HypertableId, userOrGroup,
+		policy.HypertableId, policy.MemberType, policy.Member,
 	)
 	stateEnc, err := fwhelpers.PackModel(nil, &state)
 	if err != nil {
@@ -214,23 +194,20 @@ func (r *hypertableAccessControlResource) Read(req *schema.ServiceRequest) *sche
 	res := schema.ServiceResponse{}
 
 	if req.StateID != "" {
-		hypertableId, userOrGroup := "", ""
-		parts := r.Client.ParseHypertableAccessControlStateId(
-			req.StateID,
-		)
-		if len(parts) == 2 {
-			hypertableId, userOrGroup = parts[0], parts[1]
-		}
+		id, err := r.Client.ParseHypertableAccessControlStateId(req.StateID)
 
-		if hypertableId == "" || userOrGroup == "" {
+		if err != nil {
 			// No previous state exists.
 			res.StateID = ""
 			res.StateLastUpdated = ""
 		} else {
-			// Query using existing previous state.
-			htACL, err := r.Client.ReadHypertableAccessControl(hypertableId)
+			// Query using existing previous state, which migrates
+			// state IDs without member type.
+			policy, err := findPolicy(
+				r.Client, api.PolicyKindAccessControl, id, state.UserEmail, state.GroupName,
+			)
 
-			if err != nil && err.Error() == "Not Found" {
+			if err != nil && policyNotFound(err) {
 				// No previous state exists.
 				res.StateID = ""
 				res.StateLastUpdated = ""
@@ -238,46 +215,12 @@ func (r *hypertableAccessControlResource) Read(req *schema.ServiceRequest) *sche
 				return schema.ErrorResponse(err)
 			} else {
 				// Update state with refreshed value
-				state.PolicyId = ""
-				state.HypertableId = ""
-				state.UserEmail = ""
-				state.GroupName = ""
-
-				// For a given hypertable, the list of users or groups
-				// is returned. Hence, we need to retrieve the matching
-				// hypertable ID and user or group combination.
-				found := false
-				for _, policy := range htACL.Users {
-					if policy.Member == userOrGroup {
-						state.PolicyId = policy.PolicyId
-						state.UserEmail = policy.Member
-						found = true
-						break
-					}
-				}
-				if !found {
-					for _, policy := range htACL.Groups {
-						if policy.Member == userOrGroup {
-							state.PolicyId = policy.PolicyId
-							state.GroupName = policy.Member
-							found = true
-							break
-						}
-					}
-				}
-
-				if found {
-					state.HypertableId = htACL.HypertableId
-
-					res.StateID = r.Client.GetHypertableAccessControlStateId(
-						hypertableId, userOrGroup,
-					)
-					res.StateLastUpdated = time.Now().UTC().Format(time.RFC850)
-				} else {
-					// No previous state exists.
-					res.StateID = ""
-					res.StateLastUpdated = ""
-				}
+				r.setState(&state, policy)
+
+				res.StateID = r.Client.GetHypertableAccessControlStateId(
+					policy.HypertableId, policy.MemberType, policy.Member,
+				)
+				res.StateLastUpdated = time.Now().UTC().Format(time.RFC850)
 			}
 		}
 	} else {
@@ -300,40 +243,98 @@ func (r *hypertableAccessControlResource) Read(req *schema.ServiceRequest) *sche
 func (r *hypertableAccessControlResource) Update(req *schema.ServiceRequest) *schema.ServiceResponse {
 	// logger := fwhelpers.GetLogger()
 
-	return schema.ErrorResponse(fmt.Errorf(
-		"update is not supported",
-	))
+	// Retrieve values from plan
+	var plan hypertableAccessControlResourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	var state hypertableAccessControlResourceModel
+	err = fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	existing, err := r.Client.ParseHypertableAccessControlStateId(req.StateID)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return updatePolicy(
+		r.Client, api.PolicyKindAccessControl, existing,
+		policyTarget{UserEmail: state.UserEmail, GroupName: state.GroupName},
+		policyTarget{
+			HypertableId: plan.HypertableId,
+			UserEmail:    plan.UserEmail,
+			GroupName:    plan.GroupName,
+		},
+		func() *schema.ServiceResponse { return r.create(plan) },
+		r.delete,
+	)
 }
 
 // Delete deletes the resource and removes the state on success.
 func (r *hypertableAccessControlResource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
 	// logger := fwhelpers.GetLogger()
 
-	hypertableId, userOrGroup := "", ""
-	parts := r.Client.ParseHypertableAccessControlStateId(
-		req.StateID,
-	)
-	if len(parts) == 2 {
-		hypertableId, userOrGroup = parts[0], parts[1]
-	} else {
+	id, err := r.Client.ParseHypertableAccessControlStateId(req.StateID)
+	if err != nil {
 		return schema.ErrorResponse(fmt.Errorf(
 			"invalid hypertable ID or user or group",
 		))
 	}
 
-	// Delete existing source
-	body := api.HypertableAccessControl{}
-	body.HypertableId = hypertableId
-	if strings.Contains(userOrGroup, "@") {
-		body.UserEmail = userOrGroup
-	} else {
-		body.GroupName = userOrGroup
+	if id.MemberType == "" {
+		var state hypertableAccessControlResourceModel
+		err = fwhelpers.UnpackModel(req.StateContents, &state)
+		if err != nil {
+			return schema.ErrorResponse(err)
+		}
+
+		policy, err := findPolicy(
+			r.Client, api.PolicyKindAccessControl, id, state.UserEmail, state.GroupName,
+		)
+		if err != nil && policyNotFound(err) {
+			// Already deleted.
+			return &schema.ServiceResponse{}
+		} else if err != nil {
+			return schema.ErrorResponse(err)
+		}
+		id.MemberType = policy.MemberType
 	}
 
-	err := r.Client.DeleteHypertableAccessControl(body)
+	err = r.delete(id)
 	if err != nil {
 		return schema.ErrorResponse(err)
 	}
 
 	return &schema.ServiceResponse{}
 }
+
+func (r *hypertableAccessControlResource) delete(id api.PolicyStateId) error {
+	// Delete existing source
+	body := api.HypertableAccessControl{}
+	body.HypertableId = id.HypertableId
+	if id.MemberType == api.MemberTypeUser {
+		body.UserEmail = id.Member
+	} else {
+		body.GroupName = id.Member
+	}
+
+	return r.Client.DeleteHypertableAccessControl(body)
+}
+
+// Copy a policy found on the server into the state.
+func (r *hypertableAccessControlResource) setState(state *hypertableAccessControlResourceModel, policy api.Policy) {
+	state.PolicyId = policy.PolicyId
+	state.HypertableId = policy.HypertableId
+	state.UserEmail = ""
+	state.GroupName = ""
+	if policy.MemberType == api.MemberTypeUser {
+		state.UserEmail = policy.Member
+	} else {
+		state.GroupName = policy.Member
+	}
+	state.MemberType = policy.MemberType
+}
@@ -0,0 +1,433 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zipstack/pct-plugin-framework/fwhelpers"
+	"github.com/zipstack/pct-plugin-framework/schema"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Resource implementation.
+// Owns the complete list of users and groups with access to a
+// hypertable, unlike the per member access control resource.
+type hypertableAccessPolicyResource struct {
+	Client *api.Client
+}
+
+type hypertableAccessPolicyResourceModel struct {
+	HypertableId  string         `pctsdk:"hypertable_id"`
+	Users         []string       `pctsdk:"users"`
+	Groups        []string       `pctsdk:"groups"`
+	KeepUnmanaged bool           `pctsdk:"keep_unmanaged"`
+	Managed       []accessMember `pctsdk:"managed"`
+	Unmanaged     []accessMember `pctsdk:"unmanaged"`
+}
+
+// Member with access to a hypertable, either granted or, if granted
+// out-of-band, e.g. through the UI, revoked or kept by the last apply.
+type accessMember struct {
+	MemberType string `pctsdk:"member_type"`
+	Member     string `pctsdk:"member"`
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ schema.ResourceService = &hypertableAccessPolicyResource{}
+)
+
+// Helper function to return a resource service instance.
+func NewHypertableAccessPolicyResource() schema.ResourceService {
+	return &hypertableAccessPolicyResource{}
+}
+
+// Metadata returns the resource type name.
+// It is always provider name + "_" + resource type name.
+func (r *hypertableAccessPolicyResource) Metadata(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{
+		TypeName: req.TypeName + "_hypertable_access_policy",
+	}
+}
+
+// Configure adds the provider configured client to the resource.
+func (r *hypertableAccessPolicyResource) Configure(req *schema.ServiceRequest) *schema.ServiceResponse {
+	if req.ResourceData == "" {
+		return schema.ErrorResponse(fmt.Errorf("no data provided to configure resource"))
+	}
+
+	var creds map[string]string
+	err := fwhelpers.Decode(req.ResourceData, &creds)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	client, err := api.NewClient(
+		creds["host"], creds["organisationname"],
+		creds["email"], creds["password"],
+	)
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("malformed data provided to configure resource"))
+	}
+
+	r.Client = client
+
+	return &schema.ServiceResponse{}
+}
+
+// Schema defines the schema for the resource.
+func (r *hypertableAccessPolicyResource) Schema() *schema.ServiceResponse {
+	s := &schema.Schema{
+		Description: "Authoritative hypertable access policy resource for Zipstack Cloud. " +
+			"Access granted to any other user or group is revoked, unless kept as unmanaged.",
+		Attributes: map[string]schema.Attribute{
+			"hypertable_id": &schema.StringAttribute{
+				Description: "Hypertable ID",
+				Required:    true,
+			},
+			"users": &schema.ListAttribute{
+				Description: "User emails with access",
+				Required:    true,
+				Optional:    true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "User Email",
+					Required:    true,
+				},
+			},
+			"groups": &schema.ListAttribute{
+				Description: "Group names with access",
+				Required:    true,
+				Optional:    true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "Group Name",
+					Required:    true,
+				},
+			},
+			"keep_unmanaged": &schema.BoolAttribute{
+				Description: "Keep access granted out-of-band instead of revoking it, " +
+					"it is only reported as unmanaged",
+				Required: true,
+				Optional: true,
+			},
+			"managed": accessMembersAttribute("Members granted access by the last apply"),
+			"unmanaged": accessMembersAttribute(
+				"Members granted access out-of-band, whose access was revoked by the last apply " +
+					"or is kept as per keep_unmanaged",
+			),
+		},
+	}
+
+	sEnc, err := fwhelpers.Encode(s)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		SchemaContents: sEnc,
+	}
+}
+
+func accessMembersAttribute(description string) *schema.ListAttribute {
+	return &schema.ListAttribute{
+		Description: description,
+		Computed:    true,
+		NestedAttribute: &schema.MapAttribute{
+			Description: "Member",
+			Computed:    true,
+			Attributes: map[string]schema.Attribute{
+				"member_type": &schema.StringAttribute{
+					Description: "Member type, user or group",
+					Computed:    true,
+				},
+				"member": &schema.StringAttribute{
+					Description: "User email or group name",
+					Computed:    true,
+				},
+			},
+		},
+	}
+}
+
+// Create grants access to the members, revoking any other access.
+func (r *hypertableAccessPolicyResource) Create(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	// Retrieve values from plan
+	var plan hypertableAccessPolicyResourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.apply(plan, hypertableAccessPolicyResourceModel{})
+}
+
+// Read resource information
+func (r *hypertableAccessPolicyResource) Read(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	var state hypertableAccessPolicyResourceModel
+
+	// Get current state
+	err := fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	res := schema.ServiceResponse{}
+
+	if req.StateID != "" {
+		// Query using existing previous state.
+		htACL, err := r.Client.ReadHypertableAccessControl(req.StateID)
+
+		if err != nil && policyNotFound(err) {
+			// No previous state exists.
+			res.StateID = ""
+			res.StateLastUpdated = ""
+		} else if err != nil {
+			return schema.ErrorResponse(err)
+		} else {
+			// Update state with refreshed value. Members granted
+			// out-of-band show up as drift, or as unmanaged if kept.
+			// The managed members are kept as applied, to tell them
+			// apart on the next apply.
+			configured := accessEntriesOf(state.Users, state.Groups)
+			entries := mergePolicyEntries(configured, policyEntriesOf(htACL.Policies()))
+
+			state.HypertableId = req.StateID
+			if state.KeepUnmanaged {
+				managed, unmanaged := splitPolicyEntries(entries, accessEntriesOfMembers(state.Managed))
+				entries = managed
+				state.Unmanaged = accessMembersOf(unmanaged)
+			}
+			state.Users = membersOf(entries, api.MemberTypeUser)
+			state.Groups = membersOf(entries, api.MemberTypeGroup)
+
+			res.StateID = req.StateID
+			res.StateLastUpdated = time.Now().UTC().Format(time.RFC850)
+		}
+	} else {
+		// No previous state exists.
+		res.StateID = ""
+		res.StateLastUpdated = ""
+	}
+
+	// Set refreshed state
+	stateEnc, err := fwhelpers.PackModel(nil, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	res.StateContents = stateEnc
+
+	return &res
+}
+
+// Update grants and revokes access to match the plan.
+func (r *hypertableAccessPolicyResource) Update(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	// Retrieve values from plan
+	var plan hypertableAccessPolicyResourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	var state hypertableAccessPolicyResourceModel
+	err = fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	if plan.HypertableId == state.HypertableId {
+		return r.apply(plan, state)
+	}
+
+	// Moved to another hypertable, access to the previous one
+	// is revoked once granted on the new one.
+	res := r.apply(plan, hypertableAccessPolicyResourceModel{})
+	if res.ErrorsContents != "" {
+		return res
+	}
+
+	err = r.revokeAll(state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return res
+}
+
+// Delete revokes the access of the managed members and removes the
+// state on success.
+func (r *hypertableAccessPolicyResource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	var state hypertableAccessPolicyResourceModel
+	err := fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	state.HypertableId = req.StateID
+
+	err = r.revokeAll(state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{}
+}
+
+// Grant and revoke access so that exactly the members of the plan
+// have access, apart from kept unmanaged ones. Members other than the
+// ones managed by the previous apply, i.e. granted access out-of-band,
+// are reported in the state.
+func (r *hypertableAccessPolicyResource) apply(plan hypertableAccessPolicyResourceModel, previous hypertableAccessPolicyResourceModel) *schema.ServiceResponse {
+	err := validateMembers("users", plan.Users)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	err = validateMembers("groups", plan.Groups)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	htACL, err := r.Client.ReadHypertableAccessControl(plan.HypertableId)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	desired := accessEntriesOf(plan.Users, plan.Groups)
+	changes := diffPolicyEntries(
+		desired, policyEntriesOf(htACL.Policies()), accessEntriesOfMembers(previous.Managed),
+	)
+	if plan.KeepUnmanaged {
+		changes = changes.keepUnmanaged()
+	}
+
+	// Access is granted before any other access is revoked.
+	err = r.Client.CreateHypertableAccessControls(accessControlsOfEntries(plan.HypertableId, changes.Apply))
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	err = r.Client.DeleteHypertableAccessControls(accessControlsOfEntries(plan.HypertableId, changes.Delete))
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	// Fetch updated items
+	htACL, err = r.Client.ReadHypertableAccessControl(plan.HypertableId)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	// Update state with refreshed value
+	state := hypertableAccessPolicyResourceModel{}
+	state.HypertableId = plan.HypertableId
+	state.KeepUnmanaged = plan.KeepUnmanaged
+	entries := mergePolicyEntries(desired, policyEntriesOf(htACL.Policies()))
+	if plan.KeepUnmanaged {
+		entries, _ = splitPolicyEntries(entries, desired)
+	}
+	state.Users = membersOf(entries, api.MemberTypeUser)
+	state.Groups = membersOf(entries, api.MemberTypeGroup)
+	state.Managed = accessMembersOf(desired)
+	state.Unmanaged = accessMembersOf(changes.Unmanaged)
+
+	stateEnc, err := fwhelpers.PackModel(nil, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		StateID:          plan.HypertableId,
+		StateContents:    stateEnc,
+		StateLastUpdated: time.Now().Format(time.RFC850),
+	}
+}
+
+func (r *hypertableAccessPolicyResource) revokeAll(state hypertableAccessPolicyResourceModel) error {
+	return r.Client.DeleteHypertableAccessControls(
+		accessControlsOf(state.HypertableId, state.Users, state.Groups),
+	)
+}
+
+func accessControlsOf(hypertableId string, users []string, groups []string) []api.HypertableAccessControl {
+	payloads := []api.HypertableAccessControl{}
+	for _, m := range users {
+		payloads = append(payloads, api.HypertableAccessControl{HypertableId: hypertableId, UserEmail: m})
+	}
+	for _, m := range groups {
+		payloads = append(payloads, api.HypertableAccessControl{HypertableId: hypertableId, GroupName: m})
+	}
+	return payloads
+}
+
+func accessControlsOfEntries(hypertableId string, entries []policyEntry) []api.HypertableAccessControl {
+	return accessControlsOf(
+		hypertableId, membersOf(entries, api.MemberTypeUser), membersOf(entries, api.MemberTypeGroup),
+	)
+}
+
+// Access entries have neither column nor value.
+func accessEntriesOf(users []string, groups []string) []policyEntry {
+	entries := []policyEntry{}
+	for _, m := range users {
+		entries = append(entries, policyEntry{MemberType: api.MemberTypeUser, Member: m})
+	}
+	for _, m := range groups {
+		entries = append(entries, policyEntry{MemberType: api.MemberTypeGroup, Member: m})
+	}
+	return entries
+}
+
+func accessEntriesOfMembers(members []accessMember) []policyEntry {
+	entries := []policyEntry{}
+	for _, m := range members {
+		entries = append(entries, policyEntry{MemberType: m.MemberType, Member: m.Member})
+	}
+	return entries
+}
+
+func accessMembersOf(entries []policyEntry) []accessMember {
+	members := []accessMember{}
+	for _, e := range entries {
+		members = append(members, accessMember{MemberType: e.MemberType, Member: e.Member})
+	}
+	return members
+}
+
+// Members of the entries with the member type, in order.
+func membersOf(entries []policyEntry, memberType string) []string {
+	var members []string
+	for _, e := range entries {
+		if e.MemberType == memberType {
+			members = append(members, e.Member)
+		}
+	}
+	return members
+}
+
+func validateMembers(attr string, members []string) error {
+	seen := map[string]bool{}
+	for _, m := range members {
+		if m == "" {
+			return fmt.Errorf("empty member in %s", attr)
+		}
+		if seen[m] {
+			return fmt.Errorf("duplicate member %q in %s", m, attr)
+		}
+		seen[m] = true
+	}
+	return nil
+}
+
+func containsMember(members []string, member string) bool {
+	for _, m := range members {
+		if m == member {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zipstack/pct-plugin-framework/fwhelpers"
+	"github.com/zipstack/pct-plugin-framework/schema"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Data source implementation.
+// Lists the columns of a live or scheduled hypertable.
+type hypertableColumnsDataSource struct {
+	Client *api.Client
+}
+
+type hypertableColumnsDataSourceModel struct {
+	HypertableId string             `pctsdk:"hypertable_id"`
+	Columns      []hypertableColumn `pctsdk:"columns"`
+}
+
+// Also used for the columns attribute of the hypertable resources.
+type hypertableColumn struct {
+	Name     string `pctsdk:"name"`
+	Type     string `pctsdk:"type"`
+	Nullable bool   `pctsdk:"nullable"`
+}
+
+func hypertableColumnsAttribute() *schema.ListAttribute {
+	return &schema.ListAttribute{
+		Description: "Columns of the hypertable",
+		Computed:    true,
+		NestedAttribute: &schema.MapAttribute{
+			Description: "Column",
+			Computed:    true,
+			Attributes: map[string]schema.Attribute{
+				"name": &schema.StringAttribute{
+					Description: "Name",
+					Computed:    true,
+				},
+				"type": &schema.StringAttribute{
+					Description: "Type",
+					Computed:    true,
+				},
+				"nullable": &schema.BoolAttribute{
+					Description: "Nullable",
+					Computed:    true,
+				},
+			},
+		},
+	}
+}
+
+func readHypertableColumns(client *api.Client, id string) ([]hypertableColumn, error) {
+	hcs, err := client.ReadHypertableColumns(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hypertable columns: %s", err)
+	}
+
+	columns := []hypertableColumn{}
+	for _, hc := range hcs {
+		columns = append(columns, hypertableColumn{
+			Name:     hc.Name,
+			Type:     hc.Type,
+			Nullable: hc.Nullable,
+		})
+	}
+	return columns, nil
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ schema.ResourceService = &hypertableColumnsDataSource{}
+)
+
+// Helper function to return a data source service instance.
+func NewHypertableColumnsDataSource() schema.ResourceService {
+	return &hypertableColumnsDataSource{}
+}
+
+// Metadata returns the data source type name.
+// It is always provider name + "_data_" + resource type name.
+func (r *hypertableColumnsDataSource) Metadata(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{
+		TypeName: req.TypeName + "_data_hypertable_columns",
+	}
+}
+
+// Configure adds the provider configured client to the data source.
+func (r *hypertableColumnsDataSource) Configure(req *schema.ServiceRequest) *schema.ServiceResponse {
+	if req.ResourceData == "" {
+		return schema.ErrorResponse(fmt.Errorf("no data provided to configure resource"))
+	}
+
+	var creds map[string]string
+	err := fwhelpers.Decode(req.ResourceData, &creds)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	client, err := api.NewClient(
+		creds["host"], creds["organisationname"],
+		creds["email"], creds["password"],
+	)
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("malformed data provided to configure resource"))
+	}
+
+	r.Client = client
+
+	return &schema.ServiceResponse{}
+}
+
+// Schema defines the schema for the data source.
+func (r *hypertableColumnsDataSource) Schema() *schema.ServiceResponse {
+	s := &schema.Schema{
+		Description: "Read-only column list of a hypertable for Zipstack Cloud.",
+		Attributes: map[string]schema.Attribute{
+			"hypertable_id": &schema.StringAttribute{
+				Description: "Hypertable ID",
+				Required:    true,
+			},
+			"columns": hypertableColumnsAttribute(),
+		},
+	}
+
+	sEnc, err := fwhelpers.Encode(s)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		SchemaContents: sEnc,
+	}
+}
+
+// Create lists the columns, nothing is created.
+func (r *hypertableColumnsDataSource) Create(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var plan hypertableColumnsDataSourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.list(plan.HypertableId)
+}
+
+// Read lists the columns of the hypertable again.
+func (r *hypertableColumnsDataSource) Read(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var state hypertableColumnsDataSourceModel
+	err := fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	if req.StateID == "" {
+		// No previous state exists.
+		return &schema.ServiceResponse{StateContents: req.StateContents}
+	}
+
+	return r.list(req.StateID)
+}
+
+// Update lists the columns of the changed hypertable.
+func (r *hypertableColumnsDataSource) Update(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var plan hypertableColumnsDataSourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.list(plan.HypertableId)
+}
+
+// Delete only removes the state, the hypertable is left untouched.
+func (r *hypertableColumnsDataSource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{}
+}
+
+func (r *hypertableColumnsDataSource) list(hypertableId string) *schema.ServiceResponse {
+	if hypertableId == "" {
+		return schema.ErrorResponse(fmt.Errorf("hypertable_id is required"))
+	}
+
+	columns, err := readHypertableColumns(r.Client, hypertableId)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	state := hypertableColumnsDataSourceModel{}
+	state.HypertableId = hypertableId
+	state.Columns = columns
+
+	stateEnc, err := fwhelpers.PackModel(nil, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		StateID:          hypertableId,
+		StateContents:    stateEnc,
+		StateLastUpdated: time.Now().Format(time.RFC850),
+	}
+}
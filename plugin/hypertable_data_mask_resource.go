@@ -1,8 +1,8 @@
 package plugin
 
 import (
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/zipstack/pct-plugin-framework/fwhelpers"
@@ -21,6 +21,7 @@ type hypertableDataMaskResourceModel struct {
 	HypertableId  string `pctsdk:"hypertable_id"`
 	UserEmail     string `pctsdk:"user_email"`
 	GroupName     string `pctsdk:"group_name"`
+	MemberType    string `pctsdk:"member_type"`
 	MaskingOption string `pctsdk:"masking_option"`
 	Column        string `pctsdk:"column"`
 }
@@ -91,6 +92,10 @@ func (r *hypertableDataMaskResource) Schema() *schema.ServiceResponse {
 				Required:    true,
 				Optional:    true,
 			},
+			"member_type": &schema.StringAttribute{
+				Description: "Member type, user or group",
+				Computed:    true,
+			},
 			"masking_option": &schema.StringAttribute{
 				Description: "Masking Option",
 				Required:    true,
@@ -123,15 +128,24 @@ func (r *hypertableDataMaskResource) Create(req *schema.ServiceRequest) *schema.
 		return schema.ErrorResponse(err)
 	}
 
-	if plan.UserEmail != "" && plan.GroupName != "" {
-		return schema.ErrorResponse(fmt.Errorf(
-			"both user email and group name cannot be provided",
-		))
+	return r.create(plan)
+}
+
+// Create or update the policy of the plan.
+func (r *hypertableDataMaskResource) create(plan hypertableDataMaskResourceModel) *schema.ServiceResponse {
+	memberType, member, err := policyMember(plan.UserEmail, plan.GroupName)
+	if err != nil {
+		return schema.ErrorResponse(err)
 	}
-	if plan.Column == "" {
-		return schema.ErrorResponse(fmt.Errorf(
-			"column is required",
-		))
+	col, known, err := validatePolicyColumn(r.Client, plan.HypertableId, plan.Column)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	if known {
+		err = validateMaskingOption(plan.MaskingOption, col)
+		if err != nil {
+			return schema.ErrorResponse(err)
+		}
 	}
 
 	// Generate API request body from plan
@@ -157,58 +171,23 @@ func (r *hypertableDataMaskResource) Create(req *schema.ServiceRequest) *schema.
 	state := hypertableDataMaskResourceModel{}
 
 	// Query using created state.
-	htDataMasks, err := r.Client.ReadHypertableDataMask(plan.HypertableId)
-	if err != nil {
-		return schema.ErrorResponse(err)
-	}
-
-	// For a given hypertable, the list of users or groups
-	// is returned. Hence, we need to retrieve the matching
-	// hypertable ID and user or group combination.
-	found := false
-	if len(plan.UserEmail) > 0 {
-		for _, policy := range htDataMasks.Users {
-			if policy.Member == plan.UserEmail && policy.Column == plan.Column {
-				state.PolicyId = policy.PolicyId
-				state.UserEmail = policy.Member
-				state.MaskingOption = policy.MaskingOption
-				state.Column = policy.Column
-
-				found = true
-				break
-			}
-		}
-	}
-	if !found && len(plan.GroupName) > 0 {
-		for _, policy := range htDataMasks.Groups {
-			if policy.Member == plan.GroupName && policy.Column == plan.Column {
-				state.PolicyId = policy.PolicyId
-				state.GroupName = policy.Member
-				state.MaskingOption = policy.MaskingOption
-				state.Column = policy.Column
-
-				found = true
-				break
-			}
-		}
-	}
-
-	if !found {
+	policy, err := r.Client.FindHypertablePolicy(api.PolicyKindDataMask, api.PolicyStateId{
+		HypertableId: plan.HypertableId, MemberType: memberType,
+		Member: member, Column: plan.Column,
+	})
+	if errors.Is(err, api.ErrNotFound) {
 		return schema.ErrorResponse(fmt.Errorf("failed to create data mask"))
+	} else if err != nil {
+		return schema.ErrorResponse(err)
 	}
-
-	state.HypertableId = htDataMasks.HypertableId
+	r.setState(&state, policy)
 
 	// Set refreshed state
-	userOrGroup := plan.UserEmail
-	if len(userOrGroup) == 0 {
-		userOrGroup = plan.GroupName
-	}
 	// We create a resource for each user or group, but
 	// the retrieval from provider is via hypertable ID.
 	// Hence state ID needs to be a combination of both.
 	stateId := r.Client.GetHypertableDataMaskStateId(
-		state.HypertableId, userOrGroup, state.Column,
+		policy.HypertableId, policy.MemberType, policy.Member, policy.Column,
 	)
 	stateEnc, err := fwhelpers.PackModel(nil, &state)
 	if err != nil {
@@ -237,23 +216,20 @@ func (r *hypertableDataMaskResource) Read(req *schema.ServiceRequest) *schema.Se
 	res := schema.ServiceResponse{}
 
 	if req.StateID != "" {
-		hypertableId, userOrGroup, column := "", "", ""
-		parts := r.Client.ParseHypertableDataMaskStateId(
-			req.StateID,
-		)
-		if len(parts) == 3 {
-			hypertableId, userOrGroup, column = parts[0], parts[1], parts[2]
-		}
+		id, err := r.Client.ParseHypertableDataMaskStateId(req.StateID)
 
-		if hypertableId == "" || userOrGroup == "" {
+		if err != nil {
 			// No previous state exists.
 			res.StateID = ""
 			res.StateLastUpdated = ""
 		} else {
-			// Query using existing previous state.
-			htDataMasks, err := r.Client.ReadHypertableDataMask(hypertableId)
+			// Query using existing previous state, which migrates
+			// state IDs without member type.
+			policy, err := findPolicy(
+				r.Client, api.PolicyKindDataMask, id, state.UserEmail, state.GroupName,
+			)
 
-			if err != nil && err.Error() == "404 Not Found" {
+			if err != nil && policyNotFound(err) {
 				// No previous state exists.
 				res.StateID = ""
 				res.StateLastUpdated = ""
@@ -261,54 +237,19 @@ func (r *hypertableDataMaskResource) Read(req *schema.ServiceRequest) *schema.Se
 				return schema.ErrorResponse(err)
 			} else {
 				// Update state with refreshed value
-				state.PolicyId = ""
-				state.HypertableId = ""
-				state.UserEmail = ""
-				state.GroupName = ""
-				state.MaskingOption = ""
-				state.Column = ""
-
-				// For a given hypertable, the list of users or groups
-				// is returned. Hence, we need to retrieve the matching
-				// hypertable ID and user or group combination.
-				found := false
-				for _, policy := range htDataMasks.Users {
-					if policy.Member == userOrGroup && policy.Column == column {
-						state.PolicyId = policy.PolicyId
-						state.UserEmail = policy.Member
-						state.MaskingOption = policy.MaskingOption
-						state.Column = policy.Column
-
-						found = true
-						break
-					}
-				}
-				if !found {
-					for _, policy := range htDataMasks.Groups {
-						if policy.Member == userOrGroup && policy.Column == column {
-							state.PolicyId = policy.PolicyId
-							state.GroupName = policy.Member
-							state.MaskingOption = policy.MaskingOption
-							state.Column = policy.Column
-
-							found = true
-							break
-						}
-					}
-				}
+				r.setState(&state, policy)
 
-				if found {
-					state.HypertableId = htDataMasks.HypertableId
-
-					res.StateID = r.Client.GetHypertableDataMaskStateId(
-						hypertableId, userOrGroup, column,
-					)
-					res.StateLastUpdated = time.Now().UTC().Format(time.RFC850)
-				} else {
-					// No previous state exists.
-					res.StateID = ""
-					res.StateLastUpdated = ""
+				// A column which disappeared from the hypertable
+				// is reported as drift, so that the next apply
+				// validates the column again.
+				if policyColumnMissing(r.Client, policy.HypertableId, policy.Column) {
+					state.Column = ""
 				}
+
+				res.StateID = r.Client.GetHypertableDataMaskStateId(
+					policy.HypertableId, policy.MemberType, policy.Member, policy.Column,
+				)
+				res.StateLastUpdated = time.Now().UTC().Format(time.RFC850)
 			}
 		}
 	} else {
@@ -331,41 +272,102 @@ func (r *hypertableDataMaskResource) Read(req *schema.ServiceRequest) *schema.Se
 func (r *hypertableDataMaskResource) Update(req *schema.ServiceRequest) *schema.ServiceResponse {
 	// logger := fwhelpers.GetLogger()
 
-	return schema.ErrorResponse(fmt.Errorf(
-		"update is not supported",
-	))
+	// Retrieve values from plan
+	var plan hypertableDataMaskResourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	var state hypertableDataMaskResourceModel
+	err = fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	existing, err := r.Client.ParseHypertableDataMaskStateId(req.StateID)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return updatePolicy(
+		r.Client, api.PolicyKindDataMask, existing,
+		policyTarget{UserEmail: state.UserEmail, GroupName: state.GroupName},
+		policyTarget{
+			HypertableId: plan.HypertableId,
+			UserEmail:    plan.UserEmail,
+			GroupName:    plan.GroupName,
+			Column:       plan.Column,
+		},
+		func() *schema.ServiceResponse { return r.create(plan) },
+		r.delete,
+	)
 }
 
 // Delete deletes the resource and removes the state on success.
 func (r *hypertableDataMaskResource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
 	// logger := fwhelpers.GetLogger()
 
-	hypertableId, userOrGroup, column := "", "", ""
-	parts := r.Client.ParseHypertableDataMaskStateId(
-		req.StateID,
-	)
-	if len(parts) == 3 {
-		hypertableId, userOrGroup, column = parts[0], parts[1], parts[2]
-	} else {
+	id, err := r.Client.ParseHypertableDataMaskStateId(req.StateID)
+	if err != nil {
 		return schema.ErrorResponse(fmt.Errorf(
 			"invalid hypertable ID or user or group",
 		))
 	}
 
-	// Delete existing source
-	body := api.HypertableDataMask{}
-	body.HypertableId = hypertableId
-	if strings.Contains(userOrGroup, "@") {
-		body.UserEmail = userOrGroup
-	} else {
-		body.GroupName = userOrGroup
+	if id.MemberType == "" {
+		var state hypertableDataMaskResourceModel
+		err = fwhelpers.UnpackModel(req.StateContents, &state)
+		if err != nil {
+			return schema.ErrorResponse(err)
+		}
+
+		policy, err := findPolicy(
+			r.Client, api.PolicyKindDataMask, id, state.UserEmail, state.GroupName,
+		)
+		if err != nil && policyNotFound(err) {
+			// Already deleted.
+			return &schema.ServiceResponse{}
+		} else if err != nil {
+			return schema.ErrorResponse(err)
+		}
+		id.MemberType = policy.MemberType
 	}
-	body.Column = column
 
-	err := r.Client.DeleteHypertableDataMask(body)
+	err = r.delete(id)
 	if err != nil {
 		return schema.ErrorResponse(err)
 	}
 
 	return &schema.ServiceResponse{}
 }
+
+func (r *hypertableDataMaskResource) delete(id api.PolicyStateId) error {
+	// Delete existing source
+	body := api.HypertableDataMask{}
+	body.HypertableId = id.HypertableId
+	if id.MemberType == api.MemberTypeUser {
+		body.UserEmail = id.Member
+	} else {
+		body.GroupName = id.Member
+	}
+	body.Column = id.Column
+
+	return r.Client.DeleteHypertableDataMask(body)
+}
+
+// Copy a policy found on the server into the state.
+func (r *hypertableDataMaskResource) setState(state *hypertableDataMaskResourceModel, policy api.Policy) {
+	state.PolicyId = policy.PolicyId
+	state.HypertableId = policy.HypertableId
+	state.UserEmail = ""
+	state.GroupName = ""
+	if policy.MemberType == api.MemberTypeUser {
+		state.UserEmail = policy.Member
+	} else {
+		state.GroupName = policy.Member
+	}
+	state.MemberType = policy.MemberType
+	state.MaskingOption = policy.MaskingOption
+	state.Column = policy.Column
+}
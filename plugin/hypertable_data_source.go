@@ -0,0 +1,376 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zipstack/pct-plugin-framework/fwhelpers"
+	"github.com/zipstack/pct-plugin-framework/schema"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Data source implementation.
+// Covers both live and scheduled hypertables.
+type hypertableDataSource struct {
+	Client *api.Client
+}
+
+type hypertableDataSourceModel struct {
+	Id                     string                      `pctsdk:"id"`
+	Name                   string                      `pctsdk:"name"`
+	Description            string                      `pctsdk:"description"`
+	ShortName              string                      `pctsdk:"short_name"`
+	Tags                   []string                    `pctsdk:"tags"`
+	HypertableTags         []string                    `pctsdk:"hypertable_tags"`
+	Admins                 []string                    `pctsdk:"admins"`
+	RefreshMode            string                      `pctsdk:"refresh_mode"`
+	SqlSelect              string                      `pctsdk:"sql_select"`
+	CronTiming             string                      `pctsdk:"cron_timing"`
+	CronTimingString       string                      `pctsdk:"cron_timing_string"`
+	Timezone               string                      `pctsdk:"timezone"`
+	Stages                 []hypertableDataSourceStage `pctsdk:"stages"`
+	BackingTable           string                      `pctsdk:"backing_table"`
+	BackingTableUpdateMode string                      `pctsdk:"backing_table_update_mode"`
+	PrimaryKeys            []string                    `pctsdk:"primary_keys"`
+	PartitionKeys          []string                    `pctsdk:"partition_keys"`
+	RESTEndpoint           string                      `pctsdk:"rest_endpoint"`
+	Status                 bool                        `pctsdk:"status"`
+	LastModifiedDate       string                      `pctsdk:"last_modified_date"`
+}
+
+type hypertableDataSourceStage struct {
+	ID          int64    `pctsdk:"id"`
+	Query       string   `pctsdk:"query"`
+	Name        string   `pctsdk:"name"`
+	ShortName   string   `pctsdk:"short_name"`
+	Description string   `pctsdk:"description"`
+	DependsOn   []string `pctsdk:"depends_on"`
+	RunStatus   string   `pctsdk:"run_status"`
+	StartTime   string   `pctsdk:"start_time"`
+	Duration    string   `pctsdk:"duration"`
+	Errors      int64    `pctsdk:"errors"`
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ schema.ResourceService = &hypertableDataSource{}
+)
+
+// Helper function to return a data source service instance.
+func NewHypertableDataSource() schema.ResourceService {
+	return &hypertableDataSource{}
+}
+
+// Metadata returns the data source type name.
+// It is always provider name + "_data_" + resource type name.
+func (r *hypertableDataSource) Metadata(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{
+		TypeName: req.TypeName + "_data_hypertable",
+	}
+}
+
+// Configure adds the provider configured client to the data source.
+func (r *hypertableDataSource) Configure(req *schema.ServiceRequest) *schema.ServiceResponse {
+	if req.ResourceData == "" {
+		return schema.ErrorResponse(fmt.Errorf("no data provided to configure resource"))
+	}
+
+	var creds map[string]string
+	err := fwhelpers.Decode(req.ResourceData, &creds)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	client, err := api.NewClient(
+		creds["host"], creds["organisationname"],
+		creds["email"], creds["password"],
+	)
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("malformed data provided to configure resource"))
+	}
+
+	r.Client = client
+
+	return &schema.ServiceResponse{}
+}
+
+// Schema defines the schema for the data source.
+func (r *hypertableDataSource) Schema() *schema.ServiceResponse {
+	computedString := func(description string) *schema.StringAttribute {
+		return &schema.StringAttribute{Description: description, Computed: true}
+	}
+	computedList := func(description string, nested string) *schema.ListAttribute {
+		return &schema.ListAttribute{
+			Description:     description,
+			Computed:        true,
+			NestedAttribute: computedString(nested),
+		}
+	}
+
+	s := &schema.Schema{
+		Description: "Read-only hypertable lookup for Zipstack Cloud. " +
+			"Exactly one of id, short_name or tags must be set.",
+		Attributes: map[string]schema.Attribute{
+			"id": &schema.StringAttribute{
+				Description: "ID",
+				Optional:    true,
+				Computed:    true,
+			},
+			"short_name": &schema.StringAttribute{
+				Description: "Short Name",
+				Optional:    true,
+				Computed:    true,
+			},
+			"tags": &schema.ListAttribute{
+				Description: "Tags to look up by, all of which must be present on the hypertable",
+				Optional:    true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "Tag",
+					Required:    true,
+				},
+			},
+			"hypertable_tags": &schema.ListAttribute{
+				Description: "Tags of the hypertable",
+				Computed:    true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "Tag",
+					Computed:    true,
+				},
+			},
+			"name":               computedString("Name"),
+			"description":        computedString("Description"),
+			"admins":             computedList("Admins", "Admin"),
+			"refresh_mode":       computedString("Refresh Mode"),
+			"sql_select":         computedString("SQL Select"),
+			"cron_timing":        computedString("Cron Timing"),
+			"cron_timing_string": computedString("Cron Timing String"),
+			"timezone":           computedString("Time zone of the cron timing"),
+			"stages": &schema.ListAttribute{
+				Description: "Stages",
+				Computed:    true,
+				NestedAttribute: &schema.MapAttribute{
+					Description: "Stage",
+					Computed:    true,
+					Attributes: map[string]schema.Attribute{
+						"id": &schema.IntAttribute{
+							Description: "ID",
+							Computed:    true,
+						},
+						"query":       computedString("Query"),
+						"name":        computedString("Name"),
+						"short_name":  computedString("Short Name"),
+						"description": computedString("Description"),
+						"depends_on":  computedList("Depends On", "Stage Short Name"),
+						"run_status":  computedString("Run Status"),
+						"start_time":  computedString("Start Time"),
+						"duration":    computedString("Duration"),
+						"errors": &schema.IntAttribute{
+							Description: "Errors",
+							Computed:    true,
+						},
+					},
+				},
+			},
+			"backing_table":             computedString("Backing Table"),
+			"backing_table_update_mode": computedString("Backing Table Update Mode"),
+			"primary_keys":              computedList("Primary Keys", "Primary Key"),
+			"partition_keys":            computedList("Partition Keys", "Partition Key"),
+			"rest_endpoint":             computedString("REST Endpoint"),
+			"status": &schema.BoolAttribute{
+				Description: "Status",
+				Computed:    true,
+			},
+			"last_modified_date": computedString("Last Modified Date"),
+		},
+	}
+
+	sEnc, err := fwhelpers.Encode(s)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		SchemaContents: sEnc,
+	}
+}
+
+// Create looks up the hypertable, nothing is created.
+func (r *hypertableDataSource) Create(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var plan hypertableDataSourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	err = validateLookupCriteria(plan.Id, plan.ShortName, plan.Tags)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.lookup(plan, plan.Tags)
+}
+
+// Read refreshes the hypertable looked up earlier.
+func (r *hypertableDataSource) Read(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var state hypertableDataSourceModel
+	err := fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	if req.StateID == "" {
+		// No previous state exists.
+		return &schema.ServiceResponse{StateContents: req.StateContents}
+	}
+
+	return r.lookup(hypertableDataSourceModel{Id: req.StateID}, state.Tags)
+}
+
+// Update looks up the hypertable again with the changed criteria.
+func (r *hypertableDataSource) Update(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var plan hypertableDataSourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	var state hypertableDataSourceModel
+	err = fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	// id and short_name are computed too, the plan carries over the
+	// values of the previous lookup unless they are configured. Only
+	// changed values count as criteria, the previous lookup stands when
+	// none changed.
+	criteria := hypertableDataSourceModel{Tags: plan.Tags}
+	if plan.Id != state.Id {
+		criteria.Id = plan.Id
+	}
+	if plan.ShortName != state.ShortName {
+		criteria.ShortName = plan.ShortName
+	}
+	if criteria.Id == "" && criteria.ShortName == "" && len(criteria.Tags) == 0 {
+		criteria.Id = req.StateID
+	}
+	err = validateLookupCriteria(criteria.Id, criteria.ShortName, criteria.Tags)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.lookup(criteria, plan.Tags)
+}
+
+// Delete only removes the state, the hypertable is left untouched.
+func (r *hypertableDataSource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{}
+}
+
+// Look up the hypertable by the criteria, the configured tags are kept in
+// state as they are.
+func (r *hypertableDataSource) lookup(criteria hypertableDataSourceModel, tags []string) *schema.ServiceResponse {
+	hypertable := api.Hypertable{}
+	var err error
+
+	switch {
+	case criteria.Id != "":
+		hypertable, err = r.Client.ReadHypertable(criteria.Id)
+		if err == nil && hypertable.Deleted {
+			err = api.ErrNotFound
+		}
+	case criteria.ShortName != "":
+		hypertable, err = r.Client.FindHypertableByShortName(criteria.ShortName)
+	case len(criteria.Tags) > 0:
+		hypertable, err = r.findByTags(criteria.Tags)
+	}
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("hypertable lookup failed: %s", err))
+	}
+
+	state := hypertableDataSourceModel{}
+	state.Id = hypertable.Id
+	state.Name = hypertable.Name
+	state.Description = hypertable.Description
+	state.ShortName = hypertable.ShortName
+	state.Tags = tags
+	state.HypertableTags = hypertable.Tags
+	state.Admins = hypertable.Admins
+	state.RefreshMode = hypertable.RefreshMode
+	state.SqlSelect = hypertable.SqlSelect
+
+	state.CronTiming = hypertable.CronTiming
+	state.CronTimingString = hypertable.CronTimingString
+	state.Timezone = hypertable.TimeZone
+
+	state.Stages = []hypertableDataSourceStage{}
+	for _, hs := range hypertable.Stages {
+		stage := hypertableDataSourceStage{
+			ID:          hs.ID,
+			Query:       hs.Query,
+			Name:        hs.Name,
+			ShortName:   hs.ShortName,
+			DependsOn:   hs.DependsOn,
+			Description: hs.Description,
+			RunStatus:   hs.RunStatus,
+			StartTime:   hs.StartTime,
+			Duration:    hs.Duration,
+			Errors:      hs.Errors,
+		}
+		state.Stages = append(state.Stages, stage)
+	}
+
+	state.BackingTable = hypertable.BackingTable
+	state.BackingTableUpdateMode = hypertable.BackingTableUpdateMode
+	state.PrimaryKeys = hypertable.PrimaryKeys
+	state.PartitionKeys = hypertable.PartitionKeys
+
+	state.RESTEndpoint = hypertable.RESTEndpoint
+	state.Status = hypertable.Status
+	state.LastModifiedDate = hypertable.LastModifiedDate
+
+	stateEnc, err := fwhelpers.PackModel(nil, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	t := strings.Split(hypertable.LastModifiedDate, ".")[0] + "Z"
+	tp, err := time.Parse(time.RFC3339, t)
+	if err != nil {
+		tp = time.Now()
+	}
+
+	return &schema.ServiceResponse{
+		StateID:          hypertable.Id,
+		StateContents:    stateEnc,
+		StateLastUpdated: tp.Format(time.RFC850),
+	}
+}
+
+// Exactly one hypertable must carry all of the tags.
+func (r *hypertableDataSource) findByTags(tags []string) (api.Hypertable, error) {
+	matches := []api.Hypertable{}
+
+	it := r.Client.IterateHypertables(api.ListOptions{Tag: tags[0]})
+	for it.Next() {
+		hypertable := it.Hypertable()
+		if !hypertable.Deleted && hasAllTags(hypertable.Tags, tags) {
+			matches = append(matches, hypertable)
+		}
+	}
+	if it.Err() != nil {
+		return api.Hypertable{}, it.Err()
+	}
+
+	if len(matches) == 0 {
+		return api.Hypertable{}, api.ErrNotFound
+	}
+	if len(matches) > 1 {
+		return api.Hypertable{}, fmt.Errorf(
+			"%d hypertables match tags %v", len(matches), tags,
+		)
+	}
+	return matches[0], nil
+}
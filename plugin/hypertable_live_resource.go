@@ -14,17 +14,25 @@ import (
 // Resource implementation.
 type hypertableLiveResource struct {
 	Client *api.Client
+
+	SQLFileDir string
 }
 
 type hypertableLiveResourceModel struct {
-	Id          string   `pctsdk:"id"`
-	Name        string   `pctsdk:"name"`
-	Description string   `pctsdk:"description"`
-	ShortName   string   `pctsdk:"short_name"`
-	Tags        []string `pctsdk:"tags"`
-	Admins      []string `pctsdk:"admins"`
-	RefreshMode string   `pctsdk:"refresh_mode"`
-	SqlSelect   string   `pctsdk:"sql_select"`
+	Id                string             `pctsdk:"id"`
+	Name              string             `pctsdk:"name"`
+	Description       string             `pctsdk:"description"`
+	ShortName         string             `pctsdk:"short_name"`
+	Tags              []string           `pctsdk:"tags"`
+	Admins            []string           `pctsdk:"admins"`
+	RefreshMode       string             `pctsdk:"refresh_mode"`
+	SqlSelect         string             `pctsdk:"sql_select"`
+	SqlFile           string             `pctsdk:"sql_file"`
+	SqlVariables      []sqlVariable      `pctsdk:"sql_variables"`
+	RenderedSql       string             `pctsdk:"rendered_sql"`
+	SkipSqlValidation bool               `pctsdk:"skip_sql_validation"`
+	OutputColumns     []sqlColumn        `pctsdk:"output_columns"`
+	Columns           []hypertableColumn `pctsdk:"columns"`
 }
 
 // Ensure the implementation satisfies the expected interfaces.
@@ -66,6 +74,7 @@ func (r *hypertableLiveResource) Configure(req *schema.ServiceRequest) *schema.S
 	}
 
 	r.Client = client
+	r.SQLFileDir = creds["sql_file_dir"]
 
 	return &schema.ServiceResponse{}
 }
@@ -115,9 +124,30 @@ func (r *hypertableLiveResource) Schema() *schema.ServiceResponse {
 				Required:    true,
 			},
 			"sql_select": &schema.StringAttribute{
-				Description: "SQL Select",
-				Required:    true,
+				Description: "SQL Select, a template if sql_variables are set. " +
+					"Exactly one of sql_select or sql_file is required.",
+				Required: true,
+				Optional: true,
+			},
+			"sql_file": &schema.StringAttribute{
+				Description: "Path of a file containing the SQL Select, relative " +
+					"paths are resolved against sql_file_dir of the provider",
+				Required: true,
+				Optional: true,
+			},
+			"sql_variables": sqlVariablesAttribute(),
+			"rendered_sql": &schema.StringAttribute{
+				Description: "SQL Select as sent to Zipstack Cloud",
+				Computed:    true,
 			},
+			"skip_sql_validation": &schema.BoolAttribute{
+				Description: "Skip validating the SQL Select with Zipstack Cloud, " +
+					"which is done on apply before any change is made",
+				Required: true,
+				Optional: true,
+			},
+			"output_columns": sqlColumnsAttribute("Columns of the SQL Select result"),
+			"columns":        hypertableColumnsAttribute(),
 		},
 	}
 
@@ -142,6 +172,18 @@ func (r *hypertableLiveResource) Create(req *schema.ServiceRequest) *schema.Serv
 		return schema.ErrorResponse(err)
 	}
 
+	sqlSelect, err := renderSQL(
+		r.SQLFileDir, plan.SqlSelect, plan.SqlFile, plan.SqlVariables, "sql_select", "sql_file",
+	)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	columns, err := r.validate(plan, sqlSelect)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
 	// Generate API request body from plan
 	body := api.Hypertable{}
 	body.Name = plan.Name
@@ -150,7 +192,7 @@ func (r *hypertableLiveResource) Create(req *schema.ServiceRequest) *schema.Serv
 	body.Tags = plan.Tags
 	body.Admins = plan.Admins
 	body.RefreshMode = plan.RefreshMode
-	body.SqlSelect = plan.SqlSelect
+	body.SqlSelect = sqlSelect
 
 	// Create new source
 	hypertable, err := r.Client.CreateHypertable(body)
@@ -158,6 +200,13 @@ func (r *hypertableLiveResource) Create(req *schema.ServiceRequest) *schema.Serv
 		return schema.ErrorResponse(err)
 	}
 
+	// The hypertable exists at this point and its state has to be
+	// saved, hence missing columns are left to the next refresh.
+	hypertableColumns, err := readHypertableColumns(r.Client, hypertable.Id)
+	if err != nil {
+		hypertableColumns = []hypertableColumn{}
+	}
+
 	// Update resource state with response body
 	state := hypertableLiveResourceModel{}
 	state.Id = hypertable.Id
@@ -168,6 +217,12 @@ func (r *hypertableLiveResource) Create(req *schema.ServiceRequest) *schema.Serv
 	state.Admins = plan.Admins
 	state.RefreshMode = plan.RefreshMode
 	state.SqlSelect = plan.SqlSelect
+	state.SqlFile = plan.SqlFile
+	state.SqlVariables = plan.SqlVariables
+	state.RenderedSql = sqlSelect
+	state.SkipSqlValidation = plan.SkipSqlValidation
+	state.OutputColumns = columns
+	state.Columns = hypertableColumns
 
 	// Set refreshed state
 	stateEnc, err := fwhelpers.PackModel(nil, &state)
@@ -184,7 +239,7 @@ func (r *hypertableLiveResource) Create(req *schema.ServiceRequest) *schema.Serv
 
 // Read resource information
 func (r *hypertableLiveResource) Read(req *schema.ServiceRequest) *schema.ServiceResponse {
-	// logger := fwhelpers.GetLogger()
+	logger := fwhelpers.GetLogger()
 
 	var state hypertableLiveResourceModel
 
@@ -216,7 +271,20 @@ func (r *hypertableLiveResource) Read(req *schema.ServiceRequest) *schema.Servic
 			state.Admins = hypertable.Admins
 			state.ShortName = hypertable.ShortName
 			state.RefreshMode = hypertable.RefreshMode
-			state.SqlSelect = hypertable.SqlSelect
+			state.SqlSelect, state.SqlFile = refreshSQLSource(
+				r.SQLFileDir, state.SqlSelect, state.SqlFile, state.SqlVariables,
+				hypertable.SqlSelect,
+			)
+			state.RenderedSql = hypertable.SqlSelect
+
+			// Columns are informational, a failing columns endpoint
+			// keeps the previous ones rather than failing the refresh.
+			columns, err := readHypertableColumns(r.Client, hypertable.Id)
+			if err != nil {
+				logger.Printf("hypertable %s: %s", hypertable.Id, err)
+			} else {
+				state.Columns = columns
+			}
 
 			t := strings.Split(hypertable.LastModifiedDate, ".")[0] + "Z"
 			tp, err := time.Parse(time.RFC3339, t)
@@ -254,6 +322,18 @@ func (r *hypertableLiveResource) Update(req *schema.ServiceRequest) *schema.Serv
 		return schema.ErrorResponse(err)
 	}
 
+	sqlSelect, err := renderSQL(
+		r.SQLFileDir, plan.SqlSelect, plan.SqlFile, plan.SqlVariables, "sql_select", "sql_file",
+	)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	columns, err := r.validate(plan, sqlSelect)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
 	body := api.Hypertable{}
 	body.Name = plan.Name
 	body.Description = plan.Description
@@ -261,7 +341,7 @@ func (r *hypertableLiveResource) Update(req *schema.ServiceRequest) *schema.Serv
 	body.Admins = plan.Admins
 	body.ShortName = plan.ShortName
 	body.RefreshMode = plan.RefreshMode
-	body.SqlSelect = plan.SqlSelect
+	body.SqlSelect = sqlSelect
 
 	// Update existing source
 	_, err = r.Client.UpdateHypertable(plan.Id, body)
@@ -275,6 +355,11 @@ func (r *hypertableLiveResource) Update(req *schema.ServiceRequest) *schema.Serv
 		return schema.ErrorResponse(err)
 	}
 
+	hypertableColumns, err := readHypertableColumns(r.Client, hypertable.Id)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
 	// Update state with refreshed value
 	state := hypertableLiveResourceModel{}
 	state.Id = hypertable.Id
@@ -284,7 +369,14 @@ func (r *hypertableLiveResource) Update(req *schema.ServiceRequest) *schema.Serv
 	state.Admins = hypertable.Admins
 	state.ShortName = hypertable.ShortName
 	state.RefreshMode = hypertable.RefreshMode
-	state.SqlSelect = hypertable.SqlSelect
+	state.SqlSelect, state.SqlFile = refreshSQLSource(
+		r.SQLFileDir, plan.SqlSelect, plan.SqlFile, plan.SqlVariables, hypertable.SqlSelect,
+	)
+	state.SqlVariables = plan.SqlVariables
+	state.RenderedSql = hypertable.SqlSelect
+	state.SkipSqlValidation = plan.SkipSqlValidation
+	state.OutputColumns = columns
+	state.Columns = hypertableColumns
 
 	// Set refreshed state
 	stateEnc, err := fwhelpers.PackModel(nil, &state)
@@ -305,6 +397,19 @@ func (r *hypertableLiveResource) Update(req *schema.ServiceRequest) *schema.Serv
 	}
 }
 
+// Validate the rendered SQL Select unless skipped.
+func (r *hypertableLiveResource) validate(plan hypertableLiveResourceModel, sqlSelect string) ([]sqlColumn, error) {
+	if plan.SkipSqlValidation {
+		return []sqlColumn{}, nil
+	}
+
+	source := "sql_select"
+	if plan.SqlFile != "" {
+		source = plan.SqlFile
+	}
+	return validateSQL(r.Client, sqlSelect, source)
+}
+
 // Delete deletes the resource and removes the state on success.
 func (r *hypertableLiveResource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
 	// Delete existing source
@@ -0,0 +1,476 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zipstack/pct-plugin-framework/fwhelpers"
+	"github.com/zipstack/pct-plugin-framework/schema"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Resource implementation.
+// Owns every data mask of a hypertable, declared per column as the
+// masking option of each member.
+type hypertableMaskingPolicyResource struct {
+	Client *api.Client
+}
+
+type hypertableMaskingPolicyResourceModel struct {
+	HypertableId  string          `pctsdk:"hypertable_id"`
+	Columns       []maskedColumn  `pctsdk:"columns"`
+	KeepUnmanaged bool            `pctsdk:"keep_unmanaged"`
+	Managed       []dataMaskEntry `pctsdk:"managed"`
+	Unmanaged     []dataMaskEntry `pctsdk:"unmanaged"`
+}
+
+type maskedColumn struct {
+	Column  string         `pctsdk:"column"`
+	Members []maskedMember `pctsdk:"members"`
+}
+
+type maskedMember struct {
+	MemberType    string `pctsdk:"member_type"`
+	Member        string `pctsdk:"member"`
+	MaskingOption string `pctsdk:"masking_option"`
+}
+
+// Data mask of a member on a column, either applied or, if created
+// out-of-band, e.g. through the UI, removed or kept by the last apply.
+type dataMaskEntry struct {
+	Column        string `pctsdk:"column"`
+	MemberType    string `pctsdk:"member_type"`
+	Member        string `pctsdk:"member"`
+	MaskingOption string `pctsdk:"masking_option"`
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ schema.ResourceService = &hypertableMaskingPolicyResource{}
+)
+
+// Helper function to return a resource service instance.
+func NewHypertableMaskingPolicyResource() schema.ResourceService {
+	return &hypertableMaskingPolicyResource{}
+}
+
+// Metadata returns the resource type name.
+// It is always provider name + "_" + resource type name.
+func (r *hypertableMaskingPolicyResource) Metadata(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{
+		TypeName: req.TypeName + "_hypertable_masking_policy",
+	}
+}
+
+// Configure adds the provider configured client to the resource.
+func (r *hypertableMaskingPolicyResource) Configure(req *schema.ServiceRequest) *schema.ServiceResponse {
+	if req.ResourceData == "" {
+		return schema.ErrorResponse(fmt.Errorf("no data provided to configure resource"))
+	}
+
+	var creds map[string]string
+	err := fwhelpers.Decode(req.ResourceData, &creds)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	client, err := api.NewClient(
+		creds["host"], creds["organisationname"],
+		creds["email"], creds["password"],
+	)
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("malformed data provided to configure resource"))
+	}
+
+	r.Client = client
+
+	return &schema.ServiceResponse{}
+}
+
+// Schema defines the schema for the resource.
+func (r *hypertableMaskingPolicyResource) Schema() *schema.ServiceResponse {
+	s := &schema.Schema{
+		Description: "Authoritative hypertable masking policy resource for Zipstack Cloud. " +
+			"Any other data mask of the hypertable is removed, unless kept as unmanaged.",
+		Attributes: map[string]schema.Attribute{
+			"hypertable_id": &schema.StringAttribute{
+				Description: "Hypertable ID",
+				Required:    true,
+			},
+			"columns": &schema.ListAttribute{
+				Description: "Masked columns",
+				Required:    true,
+				Optional:    true,
+				NestedAttribute: &schema.MapAttribute{
+					Description: "Masked column",
+					Required:    true,
+					Attributes: map[string]schema.Attribute{
+						"column": &schema.StringAttribute{
+							Description: "Column",
+							Required:    true,
+						},
+						"members": &schema.ListAttribute{
+							Description: "Members the column is masked for",
+							Required:    true,
+							NestedAttribute: &schema.MapAttribute{
+								Description: "Member",
+								Required:    true,
+								Attributes: map[string]schema.Attribute{
+									"member_type": &schema.StringAttribute{
+										Description: "Member type, user or group",
+										Required:    true,
+									},
+									"member": &schema.StringAttribute{
+										Description: "User email or group name",
+										Required:    true,
+									},
+									"masking_option": &schema.StringAttribute{
+										Description: "Masking Option",
+										Required:    true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"keep_unmanaged": &schema.BoolAttribute{
+				Description: "Keep data masks created out-of-band instead of removing them, " +
+					"they are only reported as unmanaged",
+				Required: true,
+				Optional: true,
+			},
+			"managed": dataMaskEntriesAttribute("Data masks applied by the last apply"),
+			"unmanaged": dataMaskEntriesAttribute(
+				"Data masks created out-of-band, which were removed by the last apply " +
+					"or are kept as per keep_unmanaged",
+			),
+		},
+	}
+
+	sEnc, err := fwhelpers.Encode(s)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		SchemaContents: sEnc,
+	}
+}
+
+func dataMaskEntriesAttribute(description string) *schema.ListAttribute {
+	return &schema.ListAttribute{
+		Description: description,
+		Computed:    true,
+		NestedAttribute: &schema.MapAttribute{
+			Description: "Data mask",
+			Computed:    true,
+			Attributes: map[string]schema.Attribute{
+				"column": &schema.StringAttribute{
+					Description: "Column",
+					Computed:    true,
+				},
+				"member_type": &schema.StringAttribute{
+					Description: "Member type, user or group",
+					Computed:    true,
+				},
+				"member": &schema.StringAttribute{
+					Description: "User email or group name",
+					Computed:    true,
+				},
+				"masking_option": &schema.StringAttribute{
+					Description: "Masking Option",
+					Computed:    true,
+				},
+			},
+		},
+	}
+}
+
+// Create masks the columns, removing any other data mask.
+func (r *hypertableMaskingPolicyResource) Create(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	// Retrieve values from plan
+	var plan hypertableMaskingPolicyResourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.apply(plan, hypertableMaskingPolicyResourceModel{})
+}
+
+// Read resource information
+func (r *hypertableMaskingPolicyResource) Read(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	var state hypertableMaskingPolicyResourceModel
+
+	// Get current state
+	err := fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	res := schema.ServiceResponse{}
+
+	if req.StateID != "" {
+		// Query using existing previous state.
+		htDataMasks, err := r.Client.ReadHypertableDataMask(req.StateID)
+
+		if err != nil && policyNotFound(err) {
+			// No previous state exists.
+			res.StateID = ""
+			res.StateLastUpdated = ""
+		} else if err != nil {
+			return schema.ErrorResponse(err)
+		} else {
+			// Update state with refreshed value. Data masks created
+			// out-of-band show up as drift, or as unmanaged if kept.
+			// The managed data masks are kept as applied, to tell
+			// them apart on the next apply.
+			configured := maskEntriesOf(state.Columns)
+			entries := mergePolicyEntries(configured, policyEntriesOf(htDataMasks.Policies()))
+
+			state.HypertableId = req.StateID
+			if state.KeepUnmanaged {
+				managed, unmanaged := splitPolicyEntries(entries, policyEntriesOfDataMasks(state.Managed))
+				entries = managed
+				state.Unmanaged = dataMaskEntriesOf(unmanaged)
+			}
+			state.Columns = maskedColumnsOf(entries)
+
+			res.StateID = req.StateID
+			res.StateLastUpdated = time.Now().UTC().Format(time.RFC850)
+		}
+	} else {
+		// No previous state exists.
+		res.StateID = ""
+		res.StateLastUpdated = ""
+	}
+
+	// Set refreshed state
+	stateEnc, err := fwhelpers.PackModel(nil, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	res.StateContents = stateEnc
+
+	return &res
+}
+
+// Update creates, changes and removes data masks to match the plan.
+func (r *hypertableMaskingPolicyResource) Update(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	// Retrieve values from plan
+	var plan hypertableMaskingPolicyResourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	var state hypertableMaskingPolicyResourceModel
+	err = fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	if plan.HypertableId == state.HypertableId {
+		return r.apply(plan, state)
+	}
+
+	// Moved to another hypertable, the data masks of the previous
+	// one are removed once in place on the new one.
+	res := r.apply(plan, hypertableMaskingPolicyResourceModel{})
+	if res.ErrorsContents != "" {
+		return res
+	}
+
+	err = r.removeAll(state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return res
+}
+
+// Delete removes the managed data masks and removes the state on
+// success.
+func (r *hypertableMaskingPolicyResource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	var state hypertableMaskingPolicyResourceModel
+	err := fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	state.HypertableId = req.StateID
+
+	err = r.removeAll(state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{}
+}
+
+// Create, change and remove data masks so that exactly those of the
+// plan exist, apart from kept unmanaged ones, with as few calls as
+// possible. Data masks other than the ones managed by the previous
+// apply, i.e. created out-of-band, are reported in the state.
+func (r *hypertableMaskingPolicyResource) apply(plan hypertableMaskingPolicyResourceModel, previous hypertableMaskingPolicyResourceModel) *schema.ServiceResponse {
+	// Validate before making any changes.
+	seen := map[string]bool{}
+	for _, c := range plan.Columns {
+		if seen[c.Column] {
+			return schema.ErrorResponse(fmt.Errorf("duplicate column %q", c.Column))
+		}
+		seen[c.Column] = true
+	}
+
+	desired := maskEntriesOf(plan.Columns)
+	err := validatePolicyEntries(desired)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	columns, err := r.Client.ReadHypertableColumns(plan.HypertableId)
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("failed to read hypertable columns: %s", err))
+	}
+	err = validatePolicyEntryColumns(plan.HypertableId, columns, desired)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	err = validateMaskingOptions(columns, desired)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	htDataMasks, err := r.Client.ReadHypertableDataMask(plan.HypertableId)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	changes := diffPolicyEntries(
+		desired, policyEntriesOf(htDataMasks.Policies()), policyEntriesOfDataMasks(previous.Managed),
+	)
+	if plan.KeepUnmanaged {
+		changes = changes.keepUnmanaged()
+	}
+
+	// Masks are in place before any other one is removed.
+	err = r.Client.CreateHypertableDataMasks(dataMasksOf(plan.HypertableId, changes.Apply))
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	err = r.Client.DeleteHypertableDataMasks(dataMasksOf(plan.HypertableId, changes.Delete))
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	// Fetch updated items
+	htDataMasks, err = r.Client.ReadHypertableDataMask(plan.HypertableId)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	// Update state with refreshed value
+	state := hypertableMaskingPolicyResourceModel{}
+	state.HypertableId = plan.HypertableId
+	state.KeepUnmanaged = plan.KeepUnmanaged
+	entries := mergePolicyEntries(desired, policyEntriesOf(htDataMasks.Policies()))
+	if plan.KeepUnmanaged {
+		entries, _ = splitPolicyEntries(entries, desired)
+	}
+	state.Columns = maskedColumnsOf(entries)
+	state.Managed = dataMaskEntriesOf(desired)
+	state.Unmanaged = dataMaskEntriesOf(changes.Unmanaged)
+
+	stateEnc, err := fwhelpers.PackModel(nil, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		StateID:          plan.HypertableId,
+		StateContents:    stateEnc,
+		StateLastUpdated: time.Now().Format(time.RFC850),
+	}
+}
+
+func (r *hypertableMaskingPolicyResource) removeAll(state hypertableMaskingPolicyResourceModel) error {
+	return r.Client.DeleteHypertableDataMasks(
+		dataMasksOf(state.HypertableId, maskEntriesOf(state.Columns)),
+	)
+}
+
+func dataMasksOf(hypertableId string, entries []policyEntry) []api.HypertableDataMask {
+	payloads := []api.HypertableDataMask{}
+	for _, e := range entries {
+		body := api.HypertableDataMask{}
+		body.HypertableId = hypertableId
+		if e.MemberType == api.MemberTypeUser {
+			body.UserEmail = e.Member
+		} else {
+			body.GroupName = e.Member
+		}
+		body.MaskingOption = e.Value
+		body.Column = e.Column
+
+		payloads = append(payloads, body)
+	}
+	return payloads
+}
+
+func maskEntriesOf(columns []maskedColumn) []policyEntry {
+	entries := []policyEntry{}
+	for _, c := range columns {
+		for _, m := range c.Members {
+			entries = append(entries, policyEntry{
+				Column: c.Column, MemberType: m.MemberType, Member: m.Member, Value: m.MaskingOption,
+			})
+		}
+	}
+	return entries
+}
+
+// Group the entries by column, in the order the columns first appear.
+func maskedColumnsOf(entries []policyEntry) []maskedColumn {
+	var columns []maskedColumn
+	index := map[string]int{}
+	for _, e := range entries {
+		i, ok := index[e.Column]
+		if !ok {
+			i = len(columns)
+			index[e.Column] = i
+			columns = append(columns, maskedColumn{Column: e.Column})
+		}
+		columns[i].Members = append(columns[i].Members, maskedMember{
+			MemberType: e.MemberType, Member: e.Member, MaskingOption: e.Value,
+		})
+	}
+	return columns
+}
+
+func dataMaskEntriesOf(entries []policyEntry) []dataMaskEntry {
+	masks := []dataMaskEntry{}
+	for _, e := range entries {
+		masks = append(masks, dataMaskEntry{
+			Column: e.Column, MemberType: e.MemberType, Member: e.Member, MaskingOption: e.Value,
+		})
+	}
+	return masks
+}
+
+func policyEntriesOfDataMasks(masks []dataMaskEntry) []policyEntry {
+	entries := []policyEntry{}
+	for _, m := range masks {
+		entries = append(entries, policyEntry{
+			Column: m.Column, MemberType: m.MemberType, Member: m.Member, Value: m.MaskingOption,
+		})
+	}
+	return entries
+}
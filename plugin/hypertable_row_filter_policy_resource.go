@@ -0,0 +1,418 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zipstack/pct-plugin-framework/fwhelpers"
+	"github.com/zipstack/pct-plugin-framework/schema"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Resource implementation.
+// Owns every row filter of a hypertable.
+type hypertableRowFilterPolicyResource struct {
+	Client    *api.Client
+	Allowlist rowFilterAllowlist
+}
+
+type hypertableRowFilterPolicyResourceModel struct {
+	HypertableId  string           `pctsdk:"hypertable_id"`
+	Filters       []rowFilterEntry `pctsdk:"filters"`
+	KeepUnmanaged bool             `pctsdk:"keep_unmanaged"`
+	Managed       []rowFilterEntry `pctsdk:"managed"`
+	Unmanaged     []rowFilterEntry `pctsdk:"unmanaged"`
+}
+
+type rowFilterEntry struct {
+	MemberType   string `pctsdk:"member_type"`
+	Member       string `pctsdk:"member"`
+	Column       string `pctsdk:"column"`
+	SQLCondition string `pctsdk:"sql_condition"`
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ schema.ResourceService = &hypertableRowFilterPolicyResource{}
+)
+
+// Helper function to return a resource service instance.
+func NewHypertableRowFilterPolicyResource() schema.ResourceService {
+	return &hypertableRowFilterPolicyResource{}
+}
+
+// Metadata returns the resource type name.
+// It is always provider name + "_" + resource type name.
+func (r *hypertableRowFilterPolicyResource) Metadata(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{
+		TypeName: req.TypeName + "_hypertable_row_filter_policy",
+	}
+}
+
+// Configure adds the provider configured client to the resource.
+func (r *hypertableRowFilterPolicyResource) Configure(req *schema.ServiceRequest) *schema.ServiceResponse {
+	if req.ResourceData == "" {
+		return schema.ErrorResponse(fmt.Errorf("no data provided to configure resource"))
+	}
+
+	var creds map[string]string
+	err := fwhelpers.Decode(req.ResourceData, &creds)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	client, err := api.NewClient(
+		creds["host"], creds["organisationname"],
+		creds["email"], creds["password"],
+	)
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("malformed data provided to configure resource"))
+	}
+
+	r.Client = client
+
+	r.Allowlist, err = rowFilterAllowlistOf(creds)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{}
+}
+
+func rowFilterEntryAttributes(computed bool) map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"member_type": &schema.StringAttribute{
+			Description: "Member type, user or group",
+			Required:    !computed,
+			Computed:    computed,
+		},
+		"member": &schema.StringAttribute{
+			Description: "User email or group name",
+			Required:    !computed,
+			Computed:    computed,
+		},
+		"column": &schema.StringAttribute{
+			Description: "Column",
+			Required:    !computed,
+			Computed:    computed,
+		},
+		"sql_condition": &schema.StringAttribute{
+			Description: "SQL Condition",
+			Required:    !computed,
+			Computed:    computed,
+		},
+	}
+}
+
+// Schema defines the schema for the resource.
+func (r *hypertableRowFilterPolicyResource) Schema() *schema.ServiceResponse {
+	s := &schema.Schema{
+		Description: "Authoritative hypertable row filter policy resource for Zipstack Cloud. " +
+			"Any other row filter of the hypertable is removed, unless kept as unmanaged.",
+		Attributes: map[string]schema.Attribute{
+			"hypertable_id": &schema.StringAttribute{
+				Description: "Hypertable ID",
+				Required:    true,
+			},
+			"filters": &schema.ListAttribute{
+				Description: "Row filters",
+				Required:    true,
+				Optional:    true,
+				NestedAttribute: &schema.MapAttribute{
+					Description: "Row filter",
+					Required:    true,
+					Attributes:  rowFilterEntryAttributes(false),
+				},
+			},
+			"keep_unmanaged": &schema.BoolAttribute{
+				Description: "Keep row filters created out-of-band instead of removing them, " +
+					"they are only reported as unmanaged",
+				Required: true,
+				Optional: true,
+			},
+			"managed": &schema.ListAttribute{
+				Description: "Row filters applied by the last apply",
+				Computed:    true,
+				NestedAttribute: &schema.MapAttribute{
+					Description: "Row filter",
+					Computed:    true,
+					Attributes:  rowFilterEntryAttributes(true),
+				},
+			},
+			"unmanaged": &schema.ListAttribute{
+				Description: "Row filters created out-of-band, which were removed by the last apply " +
+					"or are kept as per keep_unmanaged",
+				Computed: true,
+				NestedAttribute: &schema.MapAttribute{
+					Description: "Row filter",
+					Computed:    true,
+					Attributes:  rowFilterEntryAttributes(true),
+				},
+			},
+		},
+	}
+
+	sEnc, err := fwhelpers.Encode(s)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		SchemaContents: sEnc,
+	}
+}
+
+// Create adds the row filters, removing any other row filter.
+func (r *hypertableRowFilterPolicyResource) Create(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	// Retrieve values from plan
+	var plan hypertableRowFilterPolicyResourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.apply(plan, hypertableRowFilterPolicyResourceModel{})
+}
+
+// Read resource information
+func (r *hypertableRowFilterPolicyResource) Read(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	var state hypertableRowFilterPolicyResourceModel
+
+	// Get current state
+	err := fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	res := schema.ServiceResponse{}
+
+	if req.StateID != "" {
+		// Query using existing previous state.
+		htRowFilters, err := r.Client.ReadHypertableRowFilter(req.StateID)
+
+		if err != nil && policyNotFound(err) {
+			// No previous state exists.
+			res.StateID = ""
+			res.StateLastUpdated = ""
+		} else if err != nil {
+			return schema.ErrorResponse(err)
+		} else {
+			// Update state with refreshed value. Row filters created
+			// out-of-band show up as drift, or as unmanaged if kept.
+			// The managed row filters are kept as applied, to tell
+			// them apart on the next apply.
+			configured := rowFilterPolicyEntriesOf(state.Filters)
+			entries := mergePolicyEntries(configured, policyEntriesOf(htRowFilters.Policies()))
+
+			state.HypertableId = req.StateID
+			if state.KeepUnmanaged {
+				managed, unmanaged := splitPolicyEntries(entries, rowFilterPolicyEntriesOf(state.Managed))
+				state.Filters = rowFilterEntriesOf(managed)
+				state.Unmanaged = rowFilterEntriesOf(unmanaged)
+			} else {
+				state.Filters = rowFilterEntriesOf(entries)
+			}
+
+			res.StateID = req.StateID
+			res.StateLastUpdated = time.Now().UTC().Format(time.RFC850)
+		}
+	} else {
+		// No previous state exists.
+		res.StateID = ""
+		res.StateLastUpdated = ""
+	}
+
+	// Set refreshed state
+	stateEnc, err := fwhelpers.PackModel(nil, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	res.StateContents = stateEnc
+
+	return &res
+}
+
+// Update adds, changes and removes row filters to match the plan.
+func (r *hypertableRowFilterPolicyResource) Update(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	// Retrieve values from plan
+	var plan hypertableRowFilterPolicyResourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	var state hypertableRowFilterPolicyResourceModel
+	err = fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	if plan.HypertableId == state.HypertableId {
+		return r.apply(plan, state)
+	}
+
+	// Moved to another hypertable, the row filters of the previous
+	// one are removed once in place on the new one.
+	res := r.apply(plan, hypertableRowFilterPolicyResourceModel{})
+	if res.ErrorsContents != "" {
+		return res
+	}
+
+	err = r.removeAll(state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return res
+}
+
+// Delete removes the managed row filters and removes the state
+// on success.
+func (r *hypertableRowFilterPolicyResource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
+	// logger := fwhelpers.GetLogger()
+
+	var state hypertableRowFilterPolicyResourceModel
+	err := fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	state.HypertableId = req.StateID
+
+	err = r.removeAll(state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{}
+}
+
+// Add, change and remove row filters so that exactly those of the plan
+// exist, apart from kept unmanaged ones. Row filters other than the
+// ones managed by the previous apply, i.e. created out-of-band, are
+// reported in the state.
+func (r *hypertableRowFilterPolicyResource) apply(plan hypertableRowFilterPolicyResourceModel, previous hypertableRowFilterPolicyResourceModel) *schema.ServiceResponse {
+	// Validate before making any changes.
+	desired := rowFilterPolicyEntriesOf(plan.Filters)
+	err := validatePolicyEntries(desired)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	columns, err := r.Client.ReadHypertableColumns(plan.HypertableId)
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("failed to read hypertable columns: %s", err))
+	}
+	err = validatePolicyEntryColumns(plan.HypertableId, columns, desired)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	for _, e := range desired {
+		err = validateRowFilterCondition(e.Value, fmt.Sprintf(
+			"row filter of %s %q on column %q", e.MemberType, e.Member, e.Column,
+		), columns, r.Allowlist)
+		if err != nil {
+			return schema.ErrorResponse(err)
+		}
+	}
+
+	htRowFilters, err := r.Client.ReadHypertableRowFilter(plan.HypertableId)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	changes := diffPolicyEntries(
+		desired, policyEntriesOf(htRowFilters.Policies()), rowFilterPolicyEntriesOf(previous.Managed),
+	)
+	if plan.KeepUnmanaged {
+		changes = changes.keepUnmanaged()
+	}
+
+	// Filters are in place before any other one is removed.
+	err = r.Client.CreateHypertableRowFilters(rowFiltersOf(plan.HypertableId, changes.Apply))
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	err = r.Client.DeleteHypertableRowFilters(rowFiltersOf(plan.HypertableId, changes.Delete))
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	// Fetch updated items
+	htRowFilters, err = r.Client.ReadHypertableRowFilter(plan.HypertableId)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	// Update state with refreshed value
+	state := hypertableRowFilterPolicyResourceModel{}
+	state.HypertableId = plan.HypertableId
+	state.KeepUnmanaged = plan.KeepUnmanaged
+	entries := mergePolicyEntries(desired, policyEntriesOf(htRowFilters.Policies()))
+	if plan.KeepUnmanaged {
+		entries, _ = splitPolicyEntries(entries, desired)
+	}
+	state.Filters = rowFilterEntriesOf(entries)
+	state.Managed = rowFilterEntriesOf(desired)
+	state.Unmanaged = rowFilterEntriesOf(changes.Unmanaged)
+
+	stateEnc, err := fwhelpers.PackModel(nil, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		StateID:          plan.HypertableId,
+		StateContents:    stateEnc,
+		StateLastUpdated: time.Now().Format(time.RFC850),
+	}
+}
+
+func (r *hypertableRowFilterPolicyResource) removeAll(state hypertableRowFilterPolicyResourceModel) error {
+	return r.Client.DeleteHypertableRowFilters(
+		rowFiltersOf(state.HypertableId, rowFilterPolicyEntriesOf(state.Filters)),
+	)
+}
+
+func rowFiltersOf(hypertableId string, entries []policyEntry) []api.HypertableRowFilter {
+	payloads := []api.HypertableRowFilter{}
+	for _, e := range entries {
+		body := api.HypertableRowFilter{}
+		body.HypertableId = hypertableId
+		if e.MemberType == api.MemberTypeUser {
+			body.UserEmail = e.Member
+		} else {
+			body.GroupName = e.Member
+		}
+		body.SQLCondition = e.Value
+		body.Column = e.Column
+
+		payloads = append(payloads, body)
+	}
+	return payloads
+}
+
+func rowFilterPolicyEntriesOf(filters []rowFilterEntry) []policyEntry {
+	entries := []policyEntry{}
+	for _, f := range filters {
+		entries = append(entries, policyEntry{
+			Column: f.Column, MemberType: f.MemberType, Member: f.Member, Value: f.SQLCondition,
+		})
+	}
+	return entries
+}
+
+func rowFilterEntriesOf(entries []policyEntry) []rowFilterEntry {
+	filters := []rowFilterEntry{}
+	for _, e := range entries {
+		filters = append(filters, rowFilterEntry{
+			MemberType: e.MemberType, Member: e.Member, Column: e.Column, SQLCondition: e.Value,
+		})
+	}
+	return filters
+}
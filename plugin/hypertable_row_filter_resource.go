@@ -1,8 +1,8 @@
 package plugin
 
 import (
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/zipstack/pct-plugin-framework/fwhelpers"
@@ -13,7 +13,8 @@ import (
 
 // Resource implementation.
 type hypertableRowFilterResource struct {
-	Client *api.Client
+	Client    *api.Client
+	Allowlist rowFilterAllowlist
 }
 
 type hypertableRowFilterResourceModel struct {
@@ -21,6 +22,7 @@ type hypertableRowFilterResourceModel struct {
 	HypertableId string `pctsdk:"hypertable_id"`
 	UserEmail    string `pctsdk:"user_email"`
 	GroupName    string `pctsdk:"group_name"`
+	MemberType   string `pctsdk:"member_type"`
 	SQLCondition string `pctsdk:"sql_condition"`
 	Column       string `pctsdk:"column"`
 }
@@ -65,6 +67,11 @@ func (r *hypertableRowFilterResource) Configure(req *schema.ServiceRequest) *sch
 
 	r.Client = client
 
+	r.Allowlist, err = rowFilterAllowlistOf(creds)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
 	return &schema.ServiceResponse{}
 }
 
@@ -91,6 +98,10 @@ func (r *hypertableRowFilterResource) Schema() *schema.ServiceResponse {
 				Required:    true,
 				Optional:    true,
 			},
+			"member_type": &schema.StringAttribute{
+				Description: "Member type, user or group",
+				Computed:    true,
+			},
 			"sql_condition": &schema.StringAttribute{
 				Description: "SQL Condition",
 				Required:    true,
@@ -124,15 +135,26 @@ func (r *hypertableRowFilterResource) Create(req *schema.ServiceRequest) *schema
 		return schema.ErrorResponse(err)
 	}
 
-	if plan.UserEmail != "" && plan.GroupName != "" {
-		return schema.ErrorResponse(fmt.Errorf(
-			"both user email and group name cannot be provided",
-		))
+	return r.create(plan)
+}
+
+// Create or update the policy of the plan.
+func (r *hypertableRowFilterResource) create(plan hypertableRowFilterResourceModel) *schema.ServiceResponse {
+	memberType, member, err := policyMember(plan.UserEmail, plan.GroupName)
+	if err != nil {
+		return schema.ErrorResponse(err)
 	}
-	if plan.Column == "" {
-		return schema.ErrorResponse(fmt.Errorf(
-			"column is required",
-		))
+	_, _, err = validatePolicyColumn(r.Client, plan.HypertableId, plan.Column)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	if plan.SQLCondition != "" {
+		err = validateHypertableRowFilterCondition(
+			r.Client, plan.HypertableId, plan.SQLCondition, "sql_condition", r.Allowlist,
+		)
+		if err != nil {
+			return schema.ErrorResponse(err)
+		}
 	}
 
 	// Generate API request body from plan
@@ -158,58 +180,23 @@ func (r *hypertableRowFilterResource) Create(req *schema.ServiceRequest) *schema
 	state := hypertableRowFilterResourceModel{}
 
 	// Query using created state.
-	htRowFilters, err := r.Client.ReadHypertableRowFilter(plan.HypertableId)
-	if err != nil {
-		return schema.ErrorResponse(err)
-	}
-
-	// For a given hypertable, the list of users or groups
-	// is returned. Hence, we need to retrieve the matching
-	// hypertable ID and user or group combination.
-	found := false
-	if len(plan.UserEmail) > 0 {
-		for _, policy := range htRowFilters.Users {
-			if policy.Member == plan.UserEmail && policy.Column == plan.Column {
-				state.PolicyId = policy.PolicyId
-				state.UserEmail = policy.Member
-				state.SQLCondition = policy.FilterExpression
-				state.Column = policy.Column
-
-				found = true
-				break
-			}
-		}
-	}
-	if !found && len(plan.GroupName) > 0 {
-		for _, policy := range htRowFilters.Groups {
-			if policy.Member == plan.GroupName && policy.Column == plan.Column {
-				state.PolicyId = policy.PolicyId
-				state.GroupName = policy.Member
-				state.SQLCondition = policy.FilterExpression
-				state.Column = policy.Column
-
-				found = true
-				break
-			}
-		}
-	}
-
-	if !found {
+	policy, err := r.Client.FindHypertablePolicy(api.PolicyKindRowFilter, api.PolicyStateId{
+		HypertableId: plan.HypertableId, MemberType: memberType,
+		Member: member, Column: plan.Column,
+	})
+	if errors.Is(err, api.ErrNotFound) {
 		return schema.ErrorResponse(fmt.Errorf("failed to create row filter"))
+	} else if err != nil {
+		return schema.ErrorResponse(err)
 	}
-
-	state.HypertableId = htRowFilters.HypertableId
+	r.setState(&state, policy)
 
 	// Set refreshed state
-	userOrGroup := plan.UserEmail
-	if len(userOrGroup) == 0 {
-		userOrGroup = plan.GroupName
-	}
 	// We create a resource for each user or group, but
 	// the retrieval from provider is via hypertable ID.
 	// Hence state ID needs to be a combination of both.
 	stateId := r.Client.GetHypertableRowFilterStateId(
-		state.HypertableId, userOrGroup, state.Column,
+		policy.HypertableId, policy.MemberType, policy.Member, policy.Column,
 	)
 	stateEnc, err := fwhelpers.PackModel(nil, &state)
 	if err != nil {
@@ -238,23 +225,20 @@ func (r *hypertableRowFilterResource) Read(req *schema.ServiceRequest) *schema.S
 	res := schema.ServiceResponse{}
 
 	if req.StateID != "" {
-		hypertableId, userOrGroup, column := "", "", ""
-		parts := r.Client.ParseHypertableRowFilterStateId(
-			req.StateID,
-		)
-		if len(parts) == 3 {
-			hypertableId, userOrGroup, column = parts[0], parts[1], parts[2]
-		}
+		id, err := r.Client.ParseHypertableRowFilterStateId(req.StateID)
 
-		if hypertableId == "" || userOrGroup == "" {
+		if err != nil {
 			// No previous state exists.
 			res.StateID = ""
 			res.StateLastUpdated = ""
 		} else {
-			// Query using existing previous state.
-			htRowFilters, err := r.Client.ReadHypertableRowFilter(hypertableId)
+			// Query using existing previous state, which migrates
+			// state IDs without member type.
+			policy, err := findPolicy(
+				r.Client, api.PolicyKindRowFilter, id, state.UserEmail, state.GroupName,
+			)
 
-			if err != nil && err.Error() == "404 Not Found" {
+			if err != nil && policyNotFound(err) {
 				// No previous state exists.
 				res.StateID = ""
 				res.StateLastUpdated = ""
@@ -262,54 +246,19 @@ func (r *hypertableRowFilterResource) Read(req *schema.ServiceRequest) *schema.S
 				return schema.ErrorResponse(err)
 			} else {
 				// Update state with refreshed value
-				state.PolicyId = ""
-				state.HypertableId = ""
-				state.UserEmail = ""
-				state.GroupName = ""
-				state.SQLCondition = ""
-				state.Column = ""
-
-				// For a given hypertable, the list of users or groups
-				// is returned. Hence, we need to retrieve the matching
-				// hypertable ID and user or group combination.
-				found := false
-				for _, policy := range htRowFilters.Users {
-					if policy.Member == userOrGroup && policy.Column == column {
-						state.PolicyId = policy.PolicyId
-						state.UserEmail = policy.Member
-						state.SQLCondition = policy.FilterExpression
-						state.Column = policy.Column
-
-						found = true
-						break
-					}
-				}
-				if !found {
-					for _, policy := range htRowFilters.Groups {
-						if policy.Member == userOrGroup && policy.Column == column {
-							state.PolicyId = policy.PolicyId
-							state.GroupName = policy.Member
-							state.SQLCondition = policy.FilterExpression
-							state.Column = policy.Column
-
-							found = true
-							break
-						}
-					}
-				}
+				r.setState(&state, policy)
 
-				if found {
-					state.HypertableId = htRowFilters.HypertableId
-
-					res.StateID = r.Client.GetHypertableRowFilterStateId(
-						hypertableId, userOrGroup, column,
-					)
-					res.StateLastUpdated = time.Now().UTC().Format(time.RFC850)
-				} else {
-					// No previous state exists.
-					res.StateID = ""
-					res.StateLastUpdated = ""
+				// A column which disappeared from the hypertable
+				// is reported as drift, so that the next apply
+				// validates the column again.
+				if policyColumnMissing(r.Client, policy.HypertableId, policy.Column) {
+					state.Column = ""
 				}
+
+				res.StateID = r.Client.GetHypertableRowFilterStateId(
+					policy.HypertableId, policy.MemberType, policy.Member, policy.Column,
+				)
+				res.StateLastUpdated = time.Now().UTC().Format(time.RFC850)
 			}
 		}
 	} else {
@@ -332,41 +281,102 @@ func (r *hypertableRowFilterResource) Read(req *schema.ServiceRequest) *schema.S
 func (r *hypertableRowFilterResource) Update(req *schema.ServiceRequest) *schema.ServiceResponse {
 	// logger := fwhelpers.GetLogger()
 
-	return schema.ErrorResponse(fmt.Errorf(
-		"update is not supported",
-	))
+	// Retrieve values from plan
+	var plan hypertableRowFilterResourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	var state hypertableRowFilterResourceModel
+	err = fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	existing, err := r.Client.ParseHypertableRowFilterStateId(req.StateID)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return updatePolicy(
+		r.Client, api.PolicyKindRowFilter, existing,
+		policyTarget{UserEmail: state.UserEmail, GroupName: state.GroupName},
+		policyTarget{
+			HypertableId: plan.HypertableId,
+			UserEmail:    plan.UserEmail,
+			GroupName:    plan.GroupName,
+			Column:       plan.Column,
+		},
+		func() *schema.ServiceResponse { return r.create(plan) },
+		r.delete,
+	)
 }
 
 // Delete deletes the resource and removes the state on success.
 func (r *hypertableRowFilterResource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
 	// logger := fwhelpers.GetLogger()
 
-	hypertableId, userOrGroup, column := "", "", ""
-	parts := r.Client.ParseHypertableRowFilterStateId(
-		req.StateID,
-	)
-	if len(parts) == 3 {
-		hypertableId, userOrGroup, column = parts[0], parts[1], parts[2]
-	} else {
+	id, err := r.Client.ParseHypertableRowFilterStateId(req.StateID)
+	if err != nil {
 		return schema.ErrorResponse(fmt.Errorf(
 			"invalid hypertable ID or user or group",
 		))
 	}
 
-	// Delete existing source
-	body := api.HypertableRowFilter{}
-	body.HypertableId = hypertableId
-	if strings.Contains(userOrGroup, "@") {
-		body.UserEmail = userOrGroup
-	} else {
-		body.GroupName = userOrGroup
+	if id.MemberType == "" {
+		var state hypertableRowFilterResourceModel
+		err = fwhelpers.UnpackModel(req.StateContents, &state)
+		if err != nil {
+			return schema.ErrorResponse(err)
+		}
+
+		policy, err := findPolicy(
+			r.Client, api.PolicyKindRowFilter, id, state.UserEmail, state.GroupName,
+		)
+		if err != nil && policyNotFound(err) {
+			// Already deleted.
+			return &schema.ServiceResponse{}
+		} else if err != nil {
+			return schema.ErrorResponse(err)
+		}
+		id.MemberType = policy.MemberType
 	}
-	body.Column = column
 
-	err := r.Client.DeleteHypertableRowFilter(body)
+	err = r.delete(id)
 	if err != nil {
 		return schema.ErrorResponse(err)
 	}
 
 	return &schema.ServiceResponse{}
 }
+
+func (r *hypertableRowFilterResource) delete(id api.PolicyStateId) error {
+	// Delete existing source
+	body := api.HypertableRowFilter{}
+	body.HypertableId = id.HypertableId
+	if id.MemberType == api.MemberTypeUser {
+		body.UserEmail = id.Member
+	} else {
+		body.GroupName = id.Member
+	}
+	body.Column = id.Column
+
+	return r.Client.DeleteHypertableRowFilter(body)
+}
+
+// Copy a policy found on the server into the state.
+func (r *hypertableRowFilterResource) setState(state *hypertableRowFilterResourceModel, policy api.Policy) {
+	state.PolicyId = policy.PolicyId
+	state.HypertableId = policy.HypertableId
+	state.UserEmail = ""
+	state.GroupName = ""
+	if policy.MemberType == api.MemberTypeUser {
+		state.UserEmail = policy.Member
+	} else {
+		state.GroupName = policy.Member
+	}
+	state.MemberType = policy.MemberType
+	state.SQLCondition = policy.FilterExpression
+	state.Column = policy.Column
+}
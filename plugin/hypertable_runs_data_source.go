@@ -0,0 +1,341 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zipstack/pct-plugin-framework/fwhelpers"
+	"github.com/zipstack/pct-plugin-framework/schema"
+	"golang.org/x/exp/slices"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Data source implementation.
+// Lists one page of the run history of a scheduled hypertable.
+type hypertableRunsDataSource struct {
+	Client *api.Client
+}
+
+type hypertableRunsDataSourceModel struct {
+	HypertableId string                   `pctsdk:"hypertable_id"`
+	From         string                   `pctsdk:"from"`
+	To           string                   `pctsdk:"to"`
+	Status       string                   `pctsdk:"status"`
+	Page         int64                    `pctsdk:"page"`
+	PageSize     int64                    `pctsdk:"page_size"`
+	TotalPages   int64                    `pctsdk:"total_pages"`
+	TotalRuns    int64                    `pctsdk:"total_runs"`
+	Runs         []hypertableRunsDataItem `pctsdk:"runs"`
+}
+
+type hypertableRunsDataItem struct {
+	Id        string                    `pctsdk:"id"`
+	Status    string                    `pctsdk:"status"`
+	StartTime string                    `pctsdk:"start_time"`
+	EndTime   string                    `pctsdk:"end_time"`
+	Duration  string                    `pctsdk:"duration"`
+	Stages    []hypertableRunsDataStage `pctsdk:"stages"`
+}
+
+type hypertableRunsDataStage struct {
+	ID            int64    `pctsdk:"id"`
+	Name          string   `pctsdk:"name"`
+	ShortName     string   `pctsdk:"short_name"`
+	RunStatus     string   `pctsdk:"run_status"`
+	StartTime     string   `pctsdk:"start_time"`
+	Duration      string   `pctsdk:"duration"`
+	RowCount      int64    `pctsdk:"row_count"`
+	Errors        int64    `pctsdk:"errors"`
+	ErrorMessages []string `pctsdk:"error_messages"`
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ schema.ResourceService = &hypertableRunsDataSource{}
+)
+
+// Helper function to return a data source service instance.
+func NewHypertableRunsDataSource() schema.ResourceService {
+	return &hypertableRunsDataSource{}
+}
+
+// Metadata returns the data source type name.
+// It is always provider name + "_data_" + resource type name.
+func (r *hypertableRunsDataSource) Metadata(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{
+		TypeName: req.TypeName + "_data_hypertable_runs",
+	}
+}
+
+// Configure adds the provider configured client to the data source.
+func (r *hypertableRunsDataSource) Configure(req *schema.ServiceRequest) *schema.ServiceResponse {
+	if req.ResourceData == "" {
+		return schema.ErrorResponse(fmt.Errorf("no data provided to configure resource"))
+	}
+
+	var creds map[string]string
+	err := fwhelpers.Decode(req.ResourceData, &creds)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	client, err := api.NewClient(
+		creds["host"], creds["organisationname"],
+		creds["email"], creds["password"],
+	)
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("malformed data provided to configure resource"))
+	}
+
+	r.Client = client
+
+	return &schema.ServiceResponse{}
+}
+
+// Schema defines the schema for the data source.
+func (r *hypertableRunsDataSource) Schema() *schema.ServiceResponse {
+	computedString := func(description string) *schema.StringAttribute {
+		return &schema.StringAttribute{Description: description, Computed: true}
+	}
+	computedInt := func(description string) *schema.IntAttribute {
+		return &schema.IntAttribute{Description: description, Computed: true}
+	}
+
+	s := &schema.Schema{
+		Description: "Read-only run history of a scheduled hypertable " +
+			"for Zipstack Cloud, most recent run first.",
+		Attributes: map[string]schema.Attribute{
+			"hypertable_id": &schema.StringAttribute{
+				Description: "Hypertable ID",
+				Required:    true,
+			},
+			"from": &schema.StringAttribute{
+				Description: "Only runs started at or after this RFC 3339 time",
+				Required:    true,
+				Optional:    true,
+			},
+			"to": &schema.StringAttribute{
+				Description: "Only runs started before this RFC 3339 time",
+				Required:    true,
+				Optional:    true,
+			},
+			"status": &schema.StringAttribute{
+				Description: "Only runs with this status, one of PENDING, " +
+					"RUNNING, SUCCESS, FAILED or CANCELLED",
+				Required: true,
+				Optional: true,
+			},
+			"page": &schema.IntAttribute{
+				Description: "Page number, starting at 0",
+				Required:    true,
+				Optional:    true,
+			},
+			"page_size": &schema.IntAttribute{
+				Description: "Runs per page (default 100)",
+				Required:    true,
+				Optional:    true,
+			},
+			"total_pages": computedInt("Total Pages"),
+			"total_runs":  computedInt("Total Runs"),
+			"runs": &schema.ListAttribute{
+				Description: "Runs",
+				Computed:    true,
+				NestedAttribute: &schema.MapAttribute{
+					Description: "Run",
+					Computed:    true,
+					Attributes: map[string]schema.Attribute{
+						"id":         computedString("ID"),
+						"status":     computedString("Status"),
+						"start_time": computedString("Start Time"),
+						"end_time":   computedString("End Time"),
+						"duration":   computedString("Duration"),
+						"stages": &schema.ListAttribute{
+							Description: "Stages",
+							Computed:    true,
+							NestedAttribute: &schema.MapAttribute{
+								Description: "Stage",
+								Computed:    true,
+								Attributes: map[string]schema.Attribute{
+									"id":         computedInt("ID"),
+									"name":       computedString("Name"),
+									"short_name": computedString("Short Name"),
+									"run_status": computedString("Run Status"),
+									"start_time": computedString("Start Time"),
+									"duration":   computedString("Duration"),
+									"row_count":  computedInt("Row Count"),
+									"errors":     computedInt("Errors"),
+									"error_messages": &schema.ListAttribute{
+										Description:     "Error Messages",
+										Computed:        true,
+										NestedAttribute: computedString("Error Message"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	sEnc, err := fwhelpers.Encode(s)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		SchemaContents: sEnc,
+	}
+}
+
+// Create lists the runs, nothing is created.
+func (r *hypertableRunsDataSource) Create(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var plan hypertableRunsDataSourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.list(plan)
+}
+
+// Read lists the runs again with the criteria of the state.
+func (r *hypertableRunsDataSource) Read(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var state hypertableRunsDataSourceModel
+	err := fwhelpers.UnpackModel(req.StateContents, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	if req.StateID == "" {
+		// No previous state exists.
+		return &schema.ServiceResponse{StateContents: req.StateContents}
+	}
+
+	return r.list(state)
+}
+
+// Update lists the runs again with the changed criteria.
+func (r *hypertableRunsDataSource) Update(req *schema.ServiceRequest) *schema.ServiceResponse {
+	var plan hypertableRunsDataSourceModel
+	err := fwhelpers.UnpackModel(req.PlanContents, &plan)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return r.list(plan)
+}
+
+// Delete only removes the state, the run history is left untouched.
+func (r *hypertableRunsDataSource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
+	return &schema.ServiceResponse{}
+}
+
+func (r *hypertableRunsDataSource) list(criteria hypertableRunsDataSourceModel) *schema.ServiceResponse {
+	opts, err := r.runHistoryOptions(criteria)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	runPage, err := r.Client.ListHypertableRuns(criteria.HypertableId, opts)
+	if err != nil {
+		return schema.ErrorResponse(fmt.Errorf("run history lookup failed: %s", err))
+	}
+
+	state := criteria
+	state.TotalPages = int64(runPage.TotalPages)
+	state.TotalRuns = runPage.TotalElements
+
+	state.Runs = []hypertableRunsDataItem{}
+	for _, hr := range runPage.Content {
+		run := hypertableRunsDataItem{
+			Id:        hr.Id,
+			Status:    hr.Status,
+			StartTime: hr.StartTime,
+			EndTime:   hr.EndTime,
+			Duration:  hr.Duration,
+			Stages:    []hypertableRunsDataStage{},
+		}
+		for _, hs := range hr.Stages {
+			stage := hypertableRunsDataStage{
+				ID:            hs.ID,
+				Name:          hs.Name,
+				ShortName:     hs.ShortName,
+				RunStatus:     hs.RunStatus,
+				StartTime:     hs.StartTime,
+				Duration:      hs.Duration,
+				RowCount:      hs.RowCount,
+				Errors:        hs.Errors,
+				ErrorMessages: hs.ErrorMessages,
+			}
+			if stage.ErrorMessages == nil {
+				stage.ErrorMessages = []string{}
+			}
+			run.Stages = append(run.Stages, stage)
+		}
+		state.Runs = append(state.Runs, run)
+	}
+
+	stateEnc, err := fwhelpers.PackModel(nil, &state)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		StateID:          criteria.HypertableId,
+		StateContents:    stateEnc,
+		StateLastUpdated: time.Now().Format(time.RFC850),
+	}
+}
+
+// Validate the filters before sending them to the server.
+func (r *hypertableRunsDataSource) runHistoryOptions(criteria hypertableRunsDataSourceModel) (api.RunHistoryOptions, error) {
+	opts := api.RunHistoryOptions{
+		From:   criteria.From,
+		To:     criteria.To,
+		Status: strings.ToUpper(criteria.Status),
+		Page:   int(criteria.Page),
+		Size:   int(criteria.PageSize),
+	}
+
+	if criteria.HypertableId == "" {
+		return opts, fmt.Errorf("hypertable_id is required")
+	}
+
+	var from, to time.Time
+	var err error
+	if opts.From != "" {
+		from, err = time.Parse(time.RFC3339, opts.From)
+		if err != nil {
+			return opts, fmt.Errorf("invalid from %q: expected an RFC 3339 time", opts.From)
+		}
+	}
+	if opts.To != "" {
+		to, err = time.Parse(time.RFC3339, opts.To)
+		if err != nil {
+			return opts, fmt.Errorf("invalid to %q: expected an RFC 3339 time", opts.To)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return opts, fmt.Errorf("from must be before to")
+	}
+
+	statuses := []string{
+		api.RunStatusPending, api.RunStatusRunning, api.RunStatusSuccess,
+		api.RunStatusFailed, api.RunStatusCancelled,
+	}
+	if opts.Status != "" && !slices.Contains(statuses, opts.Status) {
+		return opts, fmt.Errorf(
+			"invalid status %q: expected one of %s",
+			criteria.Status, strings.Join(statuses, ", "),
+		)
+	}
+
+	if opts.Page < 0 || opts.Size < 0 {
+		return opts, fmt.Errorf("page and page_size must not be negative")
+	}
+
+	return opts, nil
+}
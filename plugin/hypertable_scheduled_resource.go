@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -9,14 +10,27 @@ import (
 	"github.com/zipstack/pct-plugin-framework/schema"
 	"golang.org/x/exp/slices"
 
+	// Embed the time zone database, plugin hosts may lack one.
+	_ "time/tzdata"
+
 	"github.com/zipstack/pct-provider-zipstack-cloud/api"
 )
 
 // Resource implementation.
 type hypertableScheduledResource struct {
 	Client *api.Client
+
+	MinScheduleInterval time.Duration
+	SQLFileDir          string
 }
 
+const (
+	defaultMinScheduleInterval = 60 * time.Second
+	defaultNextRunCount        = 5
+	maxNextRunCount            = 100
+	defaultRunTimeout          = 30 * time.Minute
+)
+
 type hypertableScheduledResourceModel struct {
 	Id                     string                     `pctsdk:"id"`
 	Name                   string                     `pctsdk:"name"`
@@ -27,25 +41,45 @@ type hypertableScheduledResourceModel struct {
 	RefreshMode            string                     `pctsdk:"refresh_mode"`
 	CronTiming             string                     `pctsdk:"cron_timing"`
 	CronTimingString       string                     `pctsdk:"cron_timing_string"`
+	Timezone               string                     `pctsdk:"timezone"`
+	NextRunCount           int64                      `pctsdk:"next_run_count"`
+	NextRunTimes           []string                   `pctsdk:"next_run_times"`
 	Stages                 []hypertableScheduledStage `pctsdk:"stages"`
+	ExecutionOrder         []string                   `pctsdk:"execution_order"`
+	SqlVariables           []sqlVariable              `pctsdk:"sql_variables"`
+	SkipSqlValidation      bool                       `pctsdk:"skip_sql_validation"`
+	Columns                []hypertableColumn         `pctsdk:"columns"`
 	BackingTable           string                     `pctsdk:"backing_table"`
 	BackingTableUpdateMode string                     `pctsdk:"backing_table_update_mode"`
 	PrimaryKeys            []string                   `pctsdk:"primary_keys"`
 	PartitionKeys          []string                   `pctsdk:"partition_keys"`
 	RESTEndpoint           string                     `pctsdk:"rest_endpoint"`
 	Status                 bool                       `pctsdk:"status"`
+	LastSuccessTime        string                     `pctsdk:"last_success_time"`
+	LastFailureTime        string                     `pctsdk:"last_failure_time"`
+	NextRunTime            string                     `pctsdk:"next_run_time"`
+	CreateBeforeDestroy    bool                       `pctsdk:"create_before_destroy"`
+	RunOnCreate            bool                       `pctsdk:"run_on_create"`
+	RunOnUpdate            bool                       `pctsdk:"run_on_update"`
+	WaitForRun             bool                       `pctsdk:"wait_for_run"`
+	RunTimeout             int64                      `pctsdk:"run_timeout"`
+	LastApplyError         string                     `pctsdk:"last_apply_error"`
 }
 
 type hypertableScheduledStage struct {
-	ID          int64  `pctsdk:"id"`
-	Query       string `pctsdk:"query"`
-	Name        string `pctsdk:"name"`
-	ShortName   string `pctsdk:"short_name"`
-	Description string `pctsdk:"description"`
-	RunStatus   string `pctsdk:"run_status"`
-	StartTime   string `pctsdk:"start_time"`
-	Duration    string `pctsdk:"duration"`
-	Errors      int64  `pctsdk:"errors"`
+	ID            int64       `pctsdk:"id"`
+	Query         string      `pctsdk:"query"`
+	QueryFile     string      `pctsdk:"query_file"`
+	RenderedQuery string      `pctsdk:"rendered_query"`
+	OutputColumns []sqlColumn `pctsdk:"output_columns"`
+	Name          string      `pctsdk:"name"`
+	ShortName     string      `pctsdk:"short_name"`
+	Description   string      `pctsdk:"description"`
+	DependsOn     []string    `pctsdk:"depends_on"`
+	RunStatus     string      `pctsdk:"run_status"`
+	StartTime     string      `pctsdk:"start_time"`
+	Duration      string      `pctsdk:"duration"`
+	Errors        int64       `pctsdk:"errors"`
 }
 
 // Ensure the implementation satisfies the expected interfaces.
@@ -88,6 +122,13 @@ func (r *hypertableScheduledResource) Configure(req *schema.ServiceRequest) *sch
 
 	r.Client = client
 
+	r.MinScheduleInterval = defaultMinScheduleInterval
+	interval, err := strconv.ParseInt(creds["min_schedule_interval"], 10, 64)
+	if err == nil && interval > 0 {
+		r.MinScheduleInterval = time.Duration(interval) * time.Second
+	}
+	r.SQLFileDir = creds["sql_file_dir"]
+
 	return &schema.ServiceResponse{}
 }
 
@@ -105,7 +146,7 @@ func (r *hypertableScheduledResource) Schema() *schema.ServiceResponse {
 				Required:    true,
 			},
 			"short_name": &schema.StringAttribute{
-				Description: "Short Name",
+				Description: "Short Name. Changing it replaces the hypertable.",
 				Required:    true,
 			},
 			"description": &schema.StringAttribute{
@@ -132,32 +173,69 @@ func (r *hypertableScheduledResource) Schema() *schema.ServiceResponse {
 				},
 			},
 			"refresh_mode": &schema.StringAttribute{
-				Description: "Refresh Mode",
+				Description: "Refresh Mode. Changing it replaces the hypertable.",
 				Required:    true,
 			},
 			"cron_timing": &schema.StringAttribute{
-				Description: "Cron Timing",
-				Required:    true,
+				Description: "Cron Timing, in the Quartz format " +
+					"\"second minute hour day-of-month month day-of-week [year]\", " +
+					"checked on apply before any change is made",
+				Required: true,
 			},
 			"cron_timing_string": &schema.StringAttribute{
-				Description: "Cron Timing String",
+				Description: "Human readable description of the cron timing",
+				Computed:    true,
+			},
+			"timezone": &schema.StringAttribute{
+				Description: "IANA time zone of the cron timing, e.g. " +
+					"\"Europe/Berlin\" (default UTC)",
+				Required: true,
+				Optional: true,
+			},
+			"next_run_count": &schema.IntAttribute{
+				Description: "Number of upcoming run times to list (default 5)",
 				Required:    true,
+				Optional:    true,
+			},
+			"next_run_times": &schema.ListAttribute{
+				Description: "Upcoming run times",
+				Computed:    true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "Run Time",
+					Computed:    true,
+				},
 			},
 			"stages": &schema.ListAttribute{
-				Description: "Stages",
-				Required:    true,
+				Description: "Stages, identified by their short names. " +
+					"Stages can be edited, added, removed and reordered in place. " +
+					"Stages run in the configured order unless dependencies " +
+					"require otherwise.",
+				Required: true,
 				NestedAttribute: &schema.MapAttribute{
 					Description: "Stage",
 					Required:    true,
 					Attributes: map[string]schema.Attribute{
 						"id": &schema.IntAttribute{
 							Description: "ID",
-							Required:    true,
+							Computed:    true,
 						},
 						"query": &schema.StringAttribute{
-							Description: "Query",
-							Required:    true,
+							Description: "Query, a template if sql_variables are set. " +
+								"Exactly one of query or query_file is required.",
+							Required: true,
+							Optional: true,
 						},
+						"query_file": &schema.StringAttribute{
+							Description: "Path of a file containing the query, relative " +
+								"paths are resolved against sql_file_dir of the provider",
+							Required: true,
+							Optional: true,
+						},
+						"rendered_query": &schema.StringAttribute{
+							Description: "Query as sent to Zipstack Cloud",
+							Computed:    true,
+						},
+						"output_columns": sqlColumnsAttribute("Columns of the query result"),
 						"name": &schema.StringAttribute{
 							Description: "Name",
 							Required:    true,
@@ -171,35 +249,61 @@ func (r *hypertableScheduledResource) Schema() *schema.ServiceResponse {
 							Required:    true,
 							Optional:    true,
 						},
-						"run_status": &schema.StringAttribute{
-							Description: "Run Status",
+						"depends_on": &schema.ListAttribute{
+							Description: "Short names of the stages which must run before this one",
 							Required:    true,
+							Optional:    true,
+							NestedAttribute: &schema.StringAttribute{
+								Description: "Stage Short Name",
+								Required:    true,
+							},
+						},
+						"run_status": &schema.StringAttribute{
+							Description: "Status of the latest run",
+							Computed:    true,
 						},
 						"start_time": &schema.StringAttribute{
-							Description: "Start Time",
-							Required:    true,
+							Description: "Start time of the latest run",
+							Computed:    true,
 						},
 						"duration": &schema.StringAttribute{
-							Description: "Duration",
-							Required:    true,
+							Description: "Duration of the latest run",
+							Computed:    true,
 						},
 						"errors": &schema.IntAttribute{
-							Description: "errors",
-							Required:    true,
+							Description: "Errors in the latest run",
+							Computed:    true,
 						},
 					},
 				},
 			},
+			"sql_variables": sqlVariablesAttribute(),
+			"skip_sql_validation": &schema.BoolAttribute{
+				Description: "Skip validating the stage queries with Zipstack Cloud, " +
+					"which is done on apply before any change is made, e.g. if a " +
+					"query reads the output of a stage which has not run yet",
+				Required: true,
+				Optional: true,
+			},
+			"columns": hypertableColumnsAttribute(),
+			"execution_order": &schema.ListAttribute{
+				Description: "Short names of the stages in the order they run",
+				Computed:    true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "Stage Short Name",
+					Computed:    true,
+				},
+			},
 			"backing_table": &schema.StringAttribute{
-				Description: "Backing Table",
+				Description: "Backing Table. Changing it replaces the hypertable.",
 				Required:    true,
 			},
 			"backing_table_update_mode": &schema.StringAttribute{
-				Description: "Backing Table Update Mode",
+				Description: "Backing Table Update Mode. Changing it replaces the hypertable.",
 				Required:    true,
 			},
 			"primary_keys": &schema.ListAttribute{
-				Description: "Primary Keys",
+				Description: "Primary Keys. Changing them replaces the hypertable.",
 				Required:    true,
 				Optional:    true,
 				NestedAttribute: &schema.StringAttribute{
@@ -208,7 +312,7 @@ func (r *hypertableScheduledResource) Schema() *schema.ServiceResponse {
 				},
 			},
 			"partition_keys": &schema.ListAttribute{
-				Description: "Partition Keys",
+				Description: "Partition Keys. Changing them replaces the hypertable.",
 				Required:    true,
 				Optional:    true,
 				NestedAttribute: &schema.StringAttribute{
@@ -225,6 +329,54 @@ func (r *hypertableScheduledResource) Schema() *schema.ServiceResponse {
 				Description: "Status",
 				Required:    true,
 			},
+			"last_success_time": &schema.StringAttribute{
+				Description: "Start time of the latest successful run",
+				Computed:    true,
+			},
+			"last_failure_time": &schema.StringAttribute{
+				Description: "Start time of the latest failed run",
+				Computed:    true,
+			},
+			"next_run_time": &schema.StringAttribute{
+				Description: "Time of the next scheduled run",
+				Computed:    true,
+			},
+			"create_before_destroy": &schema.BoolAttribute{
+				Description: "Create the replacement hypertable under a temporary " +
+					"short name before destroying the existing one, then rename " +
+					"it to the configured short name. If renaming fails, the " +
+					"temporary short name shows in the state and the next apply " +
+					"renames it again.",
+				Required: true,
+				Optional: true,
+			},
+			"run_on_create": &schema.BoolAttribute{
+				Description: "Run the stages once right after the hypertable is created",
+				Required:    true,
+				Optional:    true,
+			},
+			"run_on_update": &schema.BoolAttribute{
+				Description: "Run the stages once right after the hypertable is updated",
+				Required:    true,
+				Optional:    true,
+			},
+			"wait_for_run": &schema.BoolAttribute{
+				Description: "Wait for the triggered run to finish. A failed " +
+					"stage is reported in last_apply_error.",
+				Required: true,
+				Optional: true,
+			},
+			"run_timeout": &schema.IntAttribute{
+				Description: "Seconds to wait for the triggered run (default 1800)",
+				Required:    true,
+				Optional:    true,
+			},
+			"last_apply_error": &schema.StringAttribute{
+				Description: "Failure of the last apply after the hypertable " +
+					"had been changed, e.g. a failed run. The changes are kept " +
+					"and the apply succeeds.",
+				Computed: true,
+			},
 		},
 	}
 
@@ -249,36 +401,40 @@ func (r *hypertableScheduledResource) Create(req *schema.ServiceRequest) *schema
 		return schema.ErrorResponse(err)
 	}
 
+	return r.create(plan, plan.ShortName)
+}
+
+// Create the hypertable under the given short name, which differs from
+// the planned one for create before destroy replacements.
+func (r *hypertableScheduledResource) create(plan hypertableScheduledResourceModel, shortName string) *schema.ServiceResponse {
+	schedule, err := r.parseSchedule(plan.CronTiming, plan.Timezone)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
 	// Generate API request body from plan
 	body := api.Hypertable{}
 	body.Name = plan.Name
 	body.Description = plan.Description
-	body.ShortName = plan.ShortName
+	body.ShortName = shortName
 	body.Tags = plan.Tags
 	body.Admins = plan.Admins
 	body.RefreshMode = plan.RefreshMode
 
 	body.CronTiming = plan.CronTiming
-	body.CronTimingString = plan.CronTimingString
+	body.CronTimingString = schedule.Describe()
+	body.TimeZone = plan.Timezone
 
 	if plan.Stages != nil {
-		body.Stages = []api.HypertableScheduledStage{}
-		var id int64 = 1
-
-		for _, ps := range plan.Stages {
-			stage := api.HypertableScheduledStage{
-				ID:          id,
-				Query:       ps.Query,
-				Name:        ps.Name,
-				ShortName:   ps.ShortName,
-				Description: ps.Description,
-				RunStatus:   ps.RunStatus,
-				StartTime:   ps.StartTime,
-				Duration:    ps.Duration,
-				Errors:      ps.Errors,
-			}
-			body.Stages = append(body.Stages, stage)
+		stages, err := r.stagesRequestBody(plan.Stages, nil, plan.SqlVariables)
+		if err != nil {
+			return schema.ErrorResponse(err)
 		}
+		err = r.validateStages(&plan, stages)
+		if err != nil {
+			return schema.ErrorResponse(err)
+		}
+		body.Stages = stages
 	}
 
 	body.BackingTable = plan.BackingTable
@@ -299,7 +455,22 @@ func (r *hypertableScheduledResource) Create(req *schema.ServiceRequest) *schema
 		hypertable.Id, body, plan.Status,
 	)
 	if err != nil {
-		return schema.ErrorResponse(err)
+		return r.rollback(hypertable.Id, err)
+	}
+
+	// The hypertable is up at this point, a failed run is recorded in
+	// its state rather than rolled back.
+	ran := api.Hypertable{}
+	var runErr error
+	if plan.RunOnCreate {
+		ran, runErr = r.run(hypertable.Id, plan)
+	}
+
+	// The hypertable exists at this point and its state has to be
+	// saved, hence missing columns are left to the next refresh.
+	columns, err := readHypertableColumns(r.Client, hypertable.Id)
+	if err != nil {
+		columns = []hypertableColumn{}
 	}
 
 	// Update resource state with response body
@@ -307,34 +478,26 @@ func (r *hypertableScheduledResource) Create(req *schema.ServiceRequest) *schema
 	state.Id = hypertable.Id
 	state.Name = plan.Name
 	state.Description = plan.Description
-	state.ShortName = plan.ShortName
+	state.ShortName = shortName
 	state.Tags = plan.Tags
 	state.Admins = plan.Admins
 	state.RefreshMode = plan.RefreshMode
 
 	state.CronTiming = plan.CronTiming
-	state.CronTimingString = plan.CronTimingString
-
-	if plan.Stages != nil {
-		state.Stages = []hypertableScheduledStage{}
-		var id int64 = 1
-
-		for _, ps := range plan.Stages {
-			stage := hypertableScheduledStage{
-				ID:          id,
-				Query:       ps.Query,
-				Name:        ps.Name,
-				ShortName:   ps.ShortName,
-				Description: ps.Description,
-				RunStatus:   ps.RunStatus,
-				StartTime:   ps.StartTime,
-				Duration:    ps.Duration,
-				Errors:      ps.Errors,
-			}
-			state.Stages = append(state.Stages, stage)
-		}
+	state.CronTimingString = body.CronTimingString
+	state.Timezone = plan.Timezone
+	state.NextRunCount = plan.NextRunCount
+
+	// Report the stage statuses of the run waited for.
+	stages := body.Stages
+	if ran.Id != "" {
+		stages = ran.Stages
 	}
 
+	state.SqlVariables = plan.SqlVariables
+	state.SkipSqlValidation = plan.SkipSqlValidation
+	r.setStages(&state, stages, plan.Stages)
+
 	state.BackingTable = plan.BackingTable
 	state.BackingTableUpdateMode = plan.BackingTableUpdateMode
 	state.PrimaryKeys = plan.PrimaryKeys
@@ -343,6 +506,15 @@ func (r *hypertableScheduledResource) Create(req *schema.ServiceRequest) *schema
 	state.RESTEndpoint = plan.RESTEndpoint
 
 	state.Status = plan.Status
+	state.Columns = columns
+	if ran.Id != "" {
+		r.setRunSummary(&state, ran)
+	}
+	r.setNextRuns(&state)
+	r.setRunOptions(&state, plan)
+	if runErr != nil {
+		state.LastApplyError = fmt.Sprintf("run failed: %s", runErr)
+	}
 
 	// Set refreshed state
 	stateEnc, err := fwhelpers.PackModel(nil, &state)
@@ -359,7 +531,7 @@ func (r *hypertableScheduledResource) Create(req *schema.ServiceRequest) *schema
 
 // Read resource information
 func (r *hypertableScheduledResource) Read(req *schema.ServiceRequest) *schema.ServiceResponse {
-	// logger := fwhelpers.GetLogger()
+	logger := fwhelpers.GetLogger()
 
 	var state hypertableScheduledResourceModel
 
@@ -394,26 +566,9 @@ func (r *hypertableScheduledResource) Read(req *schema.ServiceRequest) *schema.S
 
 			state.CronTiming = hypertable.CronTiming
 			state.CronTimingString = hypertable.CronTimingString
+			state.Timezone = hypertable.TimeZone
 
-			if hypertable.Stages != nil {
-				state.Stages = []hypertableScheduledStage{}
-				var id int64 = 1
-
-				for _, ps := range hypertable.Stages {
-					stage := hypertableScheduledStage{
-						ID:          id,
-						Query:       ps.Query,
-						Name:        ps.Name,
-						ShortName:   ps.ShortName,
-						Description: ps.Description,
-						RunStatus:   ps.RunStatus,
-						StartTime:   ps.StartTime,
-						Duration:    ps.Duration,
-						Errors:      ps.Errors,
-					}
-					state.Stages = append(state.Stages, stage)
-				}
-			}
+			r.setStages(&state, hypertable.Stages, state.Stages)
 
 			state.BackingTable = hypertable.BackingTable
 			state.BackingTableUpdateMode = hypertable.BackingTableUpdateMode
@@ -423,6 +578,16 @@ func (r *hypertableScheduledResource) Read(req *schema.ServiceRequest) *schema.S
 			state.RESTEndpoint = hypertable.RESTEndpoint
 
 			state.Status = hypertable.Status
+			// Columns are informational, a failing columns endpoint
+			// keeps the previous ones rather than failing the refresh.
+			columns, err := readHypertableColumns(r.Client, hypertable.Id)
+			if err != nil {
+				logger.Printf("hypertable %s: %s", hypertable.Id, err)
+			} else {
+				state.Columns = columns
+			}
+			r.setRunSummary(&state, hypertable)
+			r.setNextRuns(&state)
 
 			t := strings.Split(hypertable.LastModifiedDate, ".")[0] + "Z"
 			tp, err := time.Parse(time.RFC3339, t)
@@ -460,6 +625,12 @@ func (r *hypertableScheduledResource) Update(req *schema.ServiceRequest) *schema
 		return schema.ErrorResponse(err)
 	}
 
+	// Validate before making any changes.
+	schedule, err := r.parseSchedule(plan.CronTiming, plan.Timezone)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
 	// Query using existing previous state.
 	hypertable, err := r.Client.ReadHypertable(req.PlanID)
 	if err != nil {
@@ -471,36 +642,83 @@ func (r *hypertableScheduledResource) Update(req *schema.ServiceRequest) *schema
 			fmt.Errorf("cannot update deleted hypertable"),
 		)
 	}
-	// Only following fields can be updated:
-	// 	- name, description, tags, admins
-	// 	- crontiming, crontimingstring, restendpoint
-	if plan.ShortName != hypertable.ShortName ||
-		plan.RefreshMode != hypertable.RefreshMode ||
-		plan.BackingTable != hypertable.BackingTable ||
-		plan.BackingTableUpdateMode != hypertable.BackingTableUpdateMode ||
-		slices.Compare(plan.PrimaryKeys, hypertable.PrimaryKeys) != 0 ||
-		slices.Compare(plan.PartitionKeys, hypertable.PartitionKeys) != 0 {
-		return schema.ErrorResponse(
-			fmt.Errorf("cannot update locked fields [shortName, refreshMode, backingTable, backingTableUpdateMode, primaryKeys, partitionKeys]"),
-		)
-	}
-	if len(plan.Stages) != len(hypertable.Stages) {
-		return schema.ErrorResponse(
-			fmt.Errorf("cannot update locked field \"stages\""),
-		)
+
+	var stages []api.HypertableScheduledStage
+	if plan.Stages != nil {
+		stages, err = r.stagesRequestBody(plan.Stages, hypertable.Stages, plan.SqlVariables)
+		if err != nil {
+			return schema.ErrorResponse(err)
+		}
+		err = r.validateStages(&plan, stages)
+		if err != nil {
+			return schema.ErrorResponse(err)
+		}
 	}
-	for idx, ps := range plan.Stages {
-		hs := hypertable.Stages[idx]
-
-		if ps.ID != hs.ID || ps.Query != hs.Query ||
-			ps.Name != hs.Name || ps.ShortName != hs.ShortName ||
-			ps.Description != hs.Description ||
-			ps.RunStatus != hs.RunStatus || ps.StartTime != hs.StartTime ||
-			ps.Duration != hs.Duration || ps.Errors != hs.Errors {
-			return schema.ErrorResponse(fmt.Errorf(fmt.Sprintf(
-				"cannot update locked field \"stages[%d]\"", idx),
+
+	// Locked fields cannot be updated, the hypertable is
+	// replaced instead.
+	id := req.PlanID
+	replaced := false
+	// Failures of an in place update are plain errors, the state keeps
+	// pointing at the hypertable and the next refresh picks up what was
+	// applied. Once a replacement exists, failures are recorded in its
+	// state instead, the existing hypertable is gone by then.
+	fail := schema.ErrorResponse
+	if r.requiresReplace(plan, hypertable) {
+		if !plan.CreateBeforeDestroy {
+			err = r.Client.DeleteHypertable(id)
+			if err != nil {
+				return schema.ErrorResponse(err)
+			}
+
+			res := r.create(plan, plan.ShortName)
+			if res.ErrorsContents != "" {
+				return schema.ErrorResponse(fmt.Errorf(
+					"hypertable %s was deleted to be replaced, but creating "+
+						"the replacement failed: %s",
+					id, res.ErrorsContents,
+				))
+			}
+			return res
+		}
+
+		// The short name is still taken by the existing hypertable,
+		// hence the replacement is created under a temporary one and
+		// renamed by the update below.
+		createPlan := plan
+		// Run once the replacement is updated like any other.
+		createPlan.RunOnCreate = false
+
+		shortName := replacementShortName(plan.ShortName)
+		res := r.create(createPlan, shortName)
+		if res.ErrorsContents != "" {
+			return res
+		}
+
+		err = r.Client.DeleteHypertable(id)
+		if err != nil {
+			// Keep the existing hypertable and drop the replacement.
+			_ = r.Client.DeleteHypertable(res.StateID)
+			return schema.ErrorResponse(err)
+		}
+
+		id = res.StateID
+		replaced = true
+		fail = func(err error) *schema.ServiceResponse {
+			return r.recordError(res, fmt.Errorf(
+				"hypertable %s replaced %s under the temporary short name %s, "+
+					"but updating it failed: %s",
+				res.StateID, req.PlanID, shortName, err,
 			))
 		}
+
+		// Stage IDs of the replacement were assigned from scratch.
+		if plan.Stages != nil {
+			stages, err = r.stagesRequestBody(plan.Stages, nil, plan.SqlVariables)
+			if err != nil {
+				return fail(err)
+			}
+		}
 	}
 
 	body := api.Hypertable{}
@@ -512,27 +730,10 @@ func (r *hypertableScheduledResource) Update(req *schema.ServiceRequest) *schema
 	body.RefreshMode = plan.RefreshMode
 
 	body.CronTiming = plan.CronTiming
-	body.CronTimingString = plan.CronTimingString
+	body.CronTimingString = schedule.Describe()
+	body.TimeZone = plan.Timezone
 
-	if plan.Stages != nil {
-		body.Stages = []api.HypertableScheduledStage{}
-		var id int64 = 1
-
-		for _, ps := range plan.Stages {
-			stage := api.HypertableScheduledStage{
-				ID:          id,
-				Query:       ps.Query,
-				Name:        ps.Name,
-				ShortName:   ps.ShortName,
-				Description: ps.Description,
-				RunStatus:   ps.RunStatus,
-				StartTime:   ps.StartTime,
-				Duration:    ps.Duration,
-				Errors:      ps.Errors,
-			}
-			body.Stages = append(body.Stages, stage)
-		}
-	}
+	body.Stages = stages
 
 	body.BackingTable = plan.BackingTable
 	body.BackingTableUpdateMode = plan.BackingTableUpdateMode
@@ -542,23 +743,49 @@ func (r *hypertableScheduledResource) Update(req *schema.ServiceRequest) *schema
 	body.RESTEndpoint = plan.RESTEndpoint
 
 	// Update existing hypertable
-	_, err = r.Client.UpdateHypertable(plan.Id, body)
+	_, err = r.Client.UpdateHypertable(id, body)
 	if err != nil {
-		return schema.ErrorResponse(err)
+		return fail(err)
 	}
 
 	// Update hypertable status.
 	err = r.Client.UpdateStatusHypertable(
-		plan.Id, body, plan.Status,
+		id, body, plan.Status,
 	)
 	if err != nil {
-		return schema.ErrorResponse(err)
+		return fail(err)
+	}
+
+	// A replacement counts as a creation. The changes are applied at
+	// this point, a failed run is recorded in the state.
+	var runErr error
+	if (replaced && plan.RunOnCreate) || (!replaced && plan.RunOnUpdate) {
+		_, runErr = r.run(id, plan)
 	}
 
 	// Fetch updated items
-	hypertable, err = r.Client.ReadHypertable(req.PlanID)
+	hypertable, err = r.Client.ReadHypertable(id)
 	if err != nil {
-		return schema.ErrorResponse(err)
+		return fail(err)
+	}
+
+	columns, err := readHypertableColumns(r.Client, id)
+	if err != nil {
+		return fail(err)
+	}
+
+	// A temporary short name is renamed by the update above. If the
+	// server did not rename a replacement, the temporary short name
+	// shows in the state and the next apply renames it again.
+	var renameErr error
+	if hypertable.ShortName != plan.ShortName {
+		renameErr = fmt.Errorf(
+			"hypertable %s was not renamed from %s to %s",
+			id, hypertable.ShortName, plan.ShortName,
+		)
+		if !replaced {
+			return fail(renameErr)
+		}
 	}
 
 	// Update state with refreshed value
@@ -573,26 +800,11 @@ func (r *hypertableScheduledResource) Update(req *schema.ServiceRequest) *schema
 
 	state.CronTiming = hypertable.CronTiming
 	state.CronTimingString = hypertable.CronTimingString
+	state.Timezone = hypertable.TimeZone
 
-	if hypertable.Stages != nil {
-		state.Stages = []hypertableScheduledStage{}
-		var id int64 = 1
-
-		for _, ps := range hypertable.Stages {
-			stage := hypertableScheduledStage{
-				ID:          id,
-				Query:       ps.Query,
-				Name:        ps.Name,
-				ShortName:   ps.ShortName,
-				Description: ps.Description,
-				RunStatus:   ps.RunStatus,
-				StartTime:   ps.StartTime,
-				Duration:    ps.Duration,
-				Errors:      ps.Errors,
-			}
-			state.Stages = append(state.Stages, stage)
-		}
-	}
+	state.SqlVariables = plan.SqlVariables
+	state.SkipSqlValidation = plan.SkipSqlValidation
+	r.setStages(&state, hypertable.Stages, plan.Stages)
 
 	state.BackingTable = hypertable.BackingTable
 	state.BackingTableUpdateMode = hypertable.BackingTableUpdateMode
@@ -602,17 +814,30 @@ func (r *hypertableScheduledResource) Update(req *schema.ServiceRequest) *schema
 	state.RESTEndpoint = hypertable.RESTEndpoint
 
 	state.Status = hypertable.Status
+	state.Columns = columns
+	state.NextRunCount = plan.NextRunCount
+	r.setRunSummary(&state, hypertable)
+	r.setNextRuns(&state)
+	r.setRunOptions(&state, plan)
+	errs := []string{}
+	if renameErr != nil {
+		errs = append(errs, renameErr.Error())
+	}
+	if runErr != nil {
+		errs = append(errs, fmt.Sprintf("run failed: %s", runErr))
+	}
+	state.LastApplyError = strings.Join(errs, "; ")
 
 	// Set refreshed state
 	stateEnc, err := fwhelpers.PackModel(nil, &state)
 	if err != nil {
-		return schema.ErrorResponse(err)
+		return fail(err)
 	}
 
 	t := strings.Split(hypertable.LastModifiedDate, ".")[0] + "Z"
 	tp, err := time.Parse(time.RFC3339, t)
 	if err != nil {
-		return schema.ErrorResponse(err)
+		return fail(err)
 	}
 
 	return &schema.ServiceResponse{
@@ -622,6 +847,369 @@ func (r *hypertableScheduledResource) Update(req *schema.ServiceRequest) *schema
 	}
 }
 
+// Delete a hypertable which could not be brought up. State is not
+// saved on failure, hence the hypertable would otherwise be orphaned
+// and its short name would block the next apply.
+func (r *hypertableScheduledResource) rollback(id string, err error) *schema.ServiceResponse {
+	rbErr := r.Client.DeleteHypertable(id)
+	if rbErr != nil {
+		return schema.ErrorResponse(fmt.Errorf(
+			"%s (rollback failed, hypertable %s needs to be "+
+				"imported or deleted manually: %s)",
+			err, id, rbErr,
+		))
+	}
+
+	return schema.ErrorResponse(err)
+}
+
+// Record the failure in the state of a hypertable which exists
+// regardless, so that it is not orphaned. The framework does not
+// define whether a state returned along with an error is saved, hence
+// the apply succeeds and the failure shows in last_apply_error.
+func (r *hypertableScheduledResource) recordError(res *schema.ServiceResponse, err error) *schema.ServiceResponse {
+	var state hypertableScheduledResourceModel
+	unpackErr := fwhelpers.UnpackModel(res.StateContents, &state)
+	if unpackErr != nil {
+		return schema.ErrorResponse(err)
+	}
+	state.LastApplyError = err.Error()
+
+	stateEnc, packErr := fwhelpers.PackModel(nil, &state)
+	if packErr != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	return &schema.ServiceResponse{
+		StateID:          res.StateID,
+		StateContents:    stateEnc,
+		StateLastUpdated: res.StateLastUpdated,
+	}
+}
+
+// Trigger a run of the stages and, if requested, wait for it to
+// finish. The hypertable is only returned after waiting.
+func (r *hypertableScheduledResource) run(id string, plan hypertableScheduledResourceModel) (api.Hypertable, error) {
+	before, err := r.Client.ReadHypertable(id)
+	if err != nil {
+		return api.Hypertable{}, err
+	}
+
+	err = r.Client.RunHypertable(id)
+	if err != nil {
+		return api.Hypertable{}, fmt.Errorf("failed to trigger hypertable run: %s", err)
+	}
+
+	if !plan.WaitForRun {
+		return api.Hypertable{}, nil
+	}
+
+	timeout := defaultRunTimeout
+	if plan.RunTimeout > 0 {
+		timeout = time.Duration(plan.RunTimeout) * time.Second
+	}
+
+	return r.Client.WaitForHypertableRun(id, before, timeout)
+}
+
+// Copy the attributes which only steer the provider from the plan.
+func (r *hypertableScheduledResource) setRunOptions(state *hypertableScheduledResourceModel, plan hypertableScheduledResourceModel) {
+	state.CreateBeforeDestroy = plan.CreateBeforeDestroy
+	state.RunOnCreate = plan.RunOnCreate
+	state.RunOnUpdate = plan.RunOnUpdate
+	state.WaitForRun = plan.WaitForRun
+	state.RunTimeout = plan.RunTimeout
+}
+
+// Whether any of the locked fields differ between plan and hypertable.
+func (r *hypertableScheduledResource) requiresReplace(plan hypertableScheduledResourceModel, hypertable api.Hypertable) bool {
+	if (plan.ShortName != hypertable.ShortName &&
+		!isReplacementShortName(plan.ShortName, hypertable.ShortName)) ||
+		plan.RefreshMode != hypertable.RefreshMode ||
+		plan.BackingTable != hypertable.BackingTable ||
+		plan.BackingTableUpdateMode != hypertable.BackingTableUpdateMode ||
+		slices.Compare(plan.PrimaryKeys, hypertable.PrimaryKeys) != 0 ||
+		slices.Compare(plan.PartitionKeys, hypertable.PartitionKeys) != 0 {
+		return true
+	}
+
+	return false
+}
+
+// Temporary short name of a create before destroy replacement, which
+// is renamed once the existing hypertable is deleted.
+func replacementShortName(shortName string) string {
+	return fmt.Sprintf("%s_tmp%d", shortName, time.Now().Unix())
+}
+
+// Whether the hypertable's short name is the temporary short name of a
+// replacement for the configured one. Such a hypertable is renamed in
+// place rather than replaced again.
+func isReplacementShortName(configured string, current string) bool {
+	suffix := strings.TrimPrefix(current, configured+"_tmp")
+	if suffix == current || suffix == "" {
+		return false
+	}
+	for _, c := range suffix {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Generate the stages of the API request body in execution order,
+// see orderStages. Stages are matched by short name against the
+// existing stages, so that edited stages keep their IDs. Added stages
+// get IDs following the highest existing one.
+func (r *hypertableScheduledResource) stagesRequestBody(planStages []hypertableScheduledStage, existing []api.HypertableScheduledStage, vars []sqlVariable) ([]api.HypertableScheduledStage, error) {
+	existingIds := map[string]int64{}
+	var nextId int64 = 1
+	for _, hs := range existing {
+		existingIds[hs.ShortName] = hs.ID
+		if hs.ID >= nextId {
+			nextId = hs.ID + 1
+		}
+	}
+
+	stages := []api.HypertableScheduledStage{}
+	seen := map[string]bool{}
+
+	for idx, ps := range planStages {
+		if ps.ShortName == "" {
+			return nil, fmt.Errorf("stages[%d]: short name is required", idx)
+		}
+		if seen[ps.ShortName] {
+			return nil, fmt.Errorf(
+				"stages[%d]: duplicate stage short name %q", idx, ps.ShortName,
+			)
+		}
+		seen[ps.ShortName] = true
+
+		query, err := renderSQL(r.SQLFileDir, ps.Query, ps.QueryFile, vars, "query", "query_file")
+		if err != nil {
+			return nil, fmt.Errorf("stage %q: %s", ps.ShortName, err)
+		}
+
+		id, ok := existingIds[ps.ShortName]
+		if !ok {
+			id = nextId
+			nextId++
+		}
+
+		stage := api.HypertableScheduledStage{
+			ID:          id,
+			Query:       query,
+			Name:        ps.Name,
+			ShortName:   ps.ShortName,
+			Description: ps.Description,
+			DependsOn:   ps.DependsOn,
+		}
+		stages = append(stages, stage)
+	}
+
+	return orderStages(stages)
+}
+
+// Validate the rendered stage queries unless skipped, keeping the
+// output columns of each stage in the plan.
+func (r *hypertableScheduledResource) validateStages(plan *hypertableScheduledResourceModel, stages []api.HypertableScheduledStage) error {
+	columns := map[string][]sqlColumn{}
+	if !plan.SkipSqlValidation {
+		for _, hs := range stages {
+			source := fmt.Sprintf("stage %q", hs.ShortName)
+			cols, err := validateSQL(r.Client, hs.Query, source)
+			if err != nil {
+				return err
+			}
+			columns[hs.ShortName] = cols
+		}
+	}
+
+	for idx, ps := range plan.Stages {
+		plan.Stages[idx].OutputColumns = columns[ps.ShortName]
+		if plan.Stages[idx].OutputColumns == nil {
+			plan.Stages[idx].OutputColumns = []sqlColumn{}
+		}
+	}
+	return nil
+}
+
+// Set the stages and their execution order from the stages of the
+// hypertable, which are in execution order. Stages are listed in the
+// configured order, followed by stages unknown to the configuration.
+func (r *hypertableScheduledResource) setStages(state *hypertableScheduledResourceModel, stages []api.HypertableScheduledStage, configured []hypertableScheduledStage) {
+	if stages == nil {
+		return
+	}
+
+	state.ExecutionOrder = []string{}
+	unlisted := map[string]bool{}
+	for _, hs := range stages {
+		state.ExecutionOrder = append(state.ExecutionOrder, hs.ShortName)
+		unlisted[hs.ShortName] = true
+	}
+
+	ordered := []api.HypertableScheduledStage{}
+	byShortName := map[string]hypertableScheduledStage{}
+	for _, cs := range configured {
+		byShortName[cs.ShortName] = cs
+		for _, hs := range stages {
+			if hs.ShortName == cs.ShortName && unlisted[hs.ShortName] {
+				ordered = append(ordered, hs)
+				unlisted[hs.ShortName] = false
+			}
+		}
+	}
+	for _, hs := range stages {
+		if unlisted[hs.ShortName] {
+			ordered = append(ordered, hs)
+		}
+	}
+
+	state.Stages = []hypertableScheduledStage{}
+	for _, ps := range ordered {
+		cs := byShortName[ps.ShortName]
+
+		// The server may only keep the order and drop the dependencies.
+		deps := ps.DependsOn
+		if deps == nil {
+			deps = cs.DependsOn
+		}
+
+		query, queryFile := refreshSQLSource(
+			r.SQLFileDir, cs.Query, cs.QueryFile, state.SqlVariables, ps.Query,
+		)
+
+		stage := hypertableScheduledStage{
+			ID:            ps.ID,
+			Query:         query,
+			QueryFile:     queryFile,
+			RenderedQuery: ps.Query,
+			OutputColumns: cs.OutputColumns,
+			Name:          ps.Name,
+			ShortName:     ps.ShortName,
+			Description:   ps.Description,
+			DependsOn:     deps,
+			RunStatus:     ps.RunStatus,
+			StartTime:     ps.StartTime,
+			Duration:      ps.Duration,
+			Errors:        ps.Errors,
+		}
+		state.Stages = append(state.Stages, stage)
+	}
+}
+
+// Refresh the summary of the latest runs. Values which are not
+// reported by the server are derived from the stage run details,
+// retaining the previously known times otherwise.
+func (r *hypertableScheduledResource) setRunSummary(state *hypertableScheduledResourceModel, hypertable api.Hypertable) {
+	if hypertable.LastSuccessTime != "" {
+		state.LastSuccessTime = hypertable.LastSuccessTime
+	}
+	if hypertable.LastFailureTime != "" {
+		state.LastFailureTime = hypertable.LastFailureTime
+	}
+	state.NextRunTime = hypertable.NextRunTime
+
+	if len(hypertable.Stages) == 0 || hypertable.Stages[0].StartTime == "" {
+		return
+	}
+
+	runStart := hypertable.Stages[0].StartTime
+	succeeded := true
+	for _, hs := range hypertable.Stages {
+		if hs.Failed() {
+			if hypertable.LastFailureTime == "" {
+				state.LastFailureTime = runStart
+			}
+			return
+		}
+		if !hs.Succeeded() {
+			succeeded = false
+		}
+	}
+	if succeeded && hypertable.LastSuccessTime == "" {
+		state.LastSuccessTime = runStart
+	}
+}
+
+// Parse the cron timing and reject schedules firing more frequently
+// than the configured minimum interval.
+func (r *hypertableScheduledResource) parseSchedule(cronTiming string, timezone string) (*cronSchedule, error) {
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := parseCron(cronTiming)
+	if err != nil {
+		return nil, err
+	}
+	schedule.Location = loc
+
+	interval := schedule.MinInterval(time.Now())
+	if interval > 0 && interval < r.MinScheduleInterval {
+		return nil, fmt.Errorf(
+			"cron timing %q runs every %s, which is more frequent than the "+
+				"minimum schedule interval of %s",
+			cronTiming, interval, r.MinScheduleInterval,
+		)
+	}
+
+	return schedule, nil
+}
+
+// Load an IANA time zone, defaulting to UTC. The zone database is
+// embedded, see the time/tzdata import.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	// Local depends on the machine running the plugin.
+	if name == "Local" {
+		return nil, fmt.Errorf("invalid timezone %q: use an IANA time zone name", name)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: not in the IANA time zone database", name)
+	}
+	return loc, nil
+}
+
+// Refresh the upcoming run times from the cron timing. The next run
+// time is only derived if the server does not report it.
+func (r *hypertableScheduledResource) setNextRuns(state *hypertableScheduledResourceModel) {
+	state.NextRunTimes = []string{}
+
+	schedule, err := parseCron(state.CronTiming)
+	if err != nil {
+		return
+	}
+	loc, err := loadTimezone(state.Timezone)
+	if err != nil {
+		return
+	}
+	schedule.Location = loc
+
+	count := int(state.NextRunCount)
+	if count <= 0 {
+		count = defaultNextRunCount
+	}
+	if count > maxNextRunCount {
+		count = maxNextRunCount
+	}
+
+	for _, t := range schedule.NextN(time.Now(), count) {
+		state.NextRunTimes = append(state.NextRunTimes, t.Format(time.RFC3339))
+	}
+
+	if state.NextRunTime == "" && len(state.NextRunTimes) > 0 {
+		state.NextRunTime = state.NextRunTimes[0]
+	}
+}
+
 // Delete deletes the resource and removes the state on success.
 func (r *hypertableScheduledResource) Delete(req *schema.ServiceRequest) *schema.ServiceResponse {
 	// Delete existing source
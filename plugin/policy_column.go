@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Families of column types, masking options are restricted by family
+// rather than by the exact SQL type.
+const (
+	columnTypeString   = "string"
+	columnTypeNumeric  = "numeric"
+	columnTypeTemporal = "temporal"
+	columnTypeOther    = "other"
+)
+
+// Masking options which only apply to some column type families.
+// Other options apply to any column, or are left to the server.
+var maskingOptionTypes = map[string][]string{
+	"SHOW_FIRST_4": {columnTypeString},
+	"SHOW_LAST_4":  {columnTypeString},
+	"EMAIL":        {columnTypeString},
+	"HASH":         {columnTypeString},
+	"DATE_YEAR":    {columnTypeTemporal},
+	"ROUND":        {columnTypeNumeric},
+}
+
+func columnTypeFamily(sqlType string) string {
+	t := strings.ToUpper(strings.TrimSpace(sqlType))
+	if idx := strings.IndexAny(t, "( "); idx >= 0 {
+		t = t[:idx]
+	}
+
+	switch t {
+	case "CHAR", "VARCHAR", "STRING", "TEXT", "NCHAR", "NVARCHAR":
+		return columnTypeString
+	case "TINYINT", "SMALLINT", "INT", "INTEGER", "BIGINT",
+		"DECIMAL", "NUMERIC", "REAL", "FLOAT", "DOUBLE":
+		return columnTypeNumeric
+	case "DATE", "TIME", "TIMESTAMP", "DATETIME":
+		return columnTypeTemporal
+	}
+	return columnTypeOther
+}
+
+// Look up a column of the hypertable. The hypertable schema is unknown
+// while it has no columns yet, e.g. before a scheduled hypertable ran
+// for the first time, which is reported as known being false.
+func findHypertableColumn(client *api.Client, hypertableId string, column string) (col api.HypertableColumn, found bool, known bool, err error) {
+	columns, err := client.ReadHypertableColumns(hypertableId)
+	if err != nil {
+		return col, false, false, fmt.Errorf("failed to read hypertable columns: %s", err)
+	}
+	if len(columns) == 0 {
+		return col, false, false, nil
+	}
+
+	for _, c := range columns {
+		if c.Name == column {
+			return c, true, true, nil
+		}
+	}
+	return col, false, true, nil
+}
+
+// Validate that the column exists in the hypertable. The column is
+// returned along with whether it is known, see findHypertableColumn.
+func validatePolicyColumn(client *api.Client, hypertableId string, column string) (api.HypertableColumn, bool, error) {
+	if column == "" {
+		return api.HypertableColumn{}, false, fmt.Errorf("column is required")
+	}
+
+	col, found, known, err := findHypertableColumn(client, hypertableId, column)
+	if err != nil || !known {
+		return col, false, err
+	}
+	if !found {
+		return col, false, fmt.Errorf(
+			"column %q does not exist in hypertable %s", column, hypertableId,
+		)
+	}
+	return col, true, nil
+}
+
+// Validate that the masking option applies to the type of the column.
+func validateMaskingOption(option string, col api.HypertableColumn) error {
+	families, ok := maskingOptionTypes[strings.ToUpper(option)]
+	if !ok {
+		return nil
+	}
+
+	family := columnTypeFamily(col.Type)
+	for _, f := range families {
+		if f == family {
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"masking option %q does not apply to column %q of type %s",
+		option, col.Name, col.Type,
+	)
+}
+
+// Validate the masking options of the entries against the types of
+// their columns, columns missing from the schema are left to
+// validatePolicyEntryColumns.
+func validateMaskingOptions(columns []api.HypertableColumn, entries []policyEntry) error {
+	byName := map[string]api.HypertableColumn{}
+	for _, c := range columns {
+		byName[c.Name] = c
+	}
+	for _, e := range entries {
+		col, ok := byName[e.Column]
+		if !ok {
+			continue
+		}
+		err := validateMaskingOption(e.Value, col)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Whether the column of a policy no longer exists in the hypertable,
+// e.g. after the SQL of the hypertable changed. The check is best
+// effort, the policy itself was read fine if the columns cannot be.
+func policyColumnMissing(client *api.Client, hypertableId string, column string) bool {
+	_, found, known, err := findHypertableColumn(client, hypertableId, column)
+	return err == nil && known && !found
+}
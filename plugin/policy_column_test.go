@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+func TestColumnTypeFamily(t *testing.T) {
+	tests := []struct {
+		sqlType string
+		want    string
+	}{
+		{"varchar", columnTypeString},
+		{"VARCHAR(255)", columnTypeString},
+		{" text ", columnTypeString},
+		{"bigint", columnTypeNumeric},
+		{"DECIMAL(10,2)", columnTypeNumeric},
+		{"double precision", columnTypeNumeric},
+		{"timestamp with time zone", columnTypeTemporal},
+		{"DATE", columnTypeTemporal},
+		{"boolean", columnTypeOther},
+		{"", columnTypeOther},
+	}
+
+	for _, tt := range tests {
+		if got := columnTypeFamily(tt.sqlType); got != tt.want {
+			t.Errorf("columnTypeFamily(%q) = %q, want %q", tt.sqlType, got, tt.want)
+		}
+	}
+}
+
+func TestValidateMaskingOption(t *testing.T) {
+	tests := []struct {
+		option  string
+		sqlType string
+		valid   bool
+	}{
+		{"SHOW_FIRST_4", "varchar", true},
+		{"show_last_4", "text", true},
+		{"EMAIL", "varchar(320)", true},
+		{"HASH", "char(8)", true},
+		{"DATE_YEAR", "timestamp", true},
+		{"ROUND", "decimal(10,2)", true},
+		{"SHOW_FIRST_4", "bigint", false},
+		{"EMAIL", "date", false},
+		{"HASH", "boolean", false},
+		{"DATE_YEAR", "varchar", false},
+		{"ROUND", "text", false},
+		// Options without type restrictions are left to the server.
+		{"NULLIFY", "bigint", true},
+		{"FULL", "boolean", true},
+	}
+
+	for _, tt := range tests {
+		col := api.HypertableColumn{Name: "c", Type: tt.sqlType}
+		err := validateMaskingOption(tt.option, col)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateMaskingOption(%q, %q) = %v, want valid %t", tt.option, tt.sqlType, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateMaskingOptions(t *testing.T) {
+	columns := []api.HypertableColumn{
+		{Name: "email", Type: "varchar"},
+		{Name: "amount", Type: "decimal(10,2)"},
+	}
+
+	valid := []policyEntry{
+		{Column: "email", MemberType: api.MemberTypeUser, Member: "a@example.com", Value: "EMAIL"},
+		{Column: "amount", MemberType: api.MemberTypeGroup, Member: "analysts", Value: "ROUND"},
+		{Column: "missing", MemberType: api.MemberTypeGroup, Member: "analysts", Value: "ROUND"},
+	}
+	if err := validateMaskingOptions(columns, valid); err != nil {
+		t.Errorf("validateMaskingOptions = %s, want no error", err)
+	}
+
+	invalid := append(valid, policyEntry{
+		Column: "amount", MemberType: api.MemberTypeUser, Member: "b@example.com", Value: "EMAIL",
+	})
+	if err := validateMaskingOptions(columns, invalid); err == nil {
+		t.Error("validateMaskingOptions succeeded, want an error for EMAIL on a decimal column")
+	}
+}
@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zipstack/pct-plugin-framework/schema"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Member type and member of a policy, from whichever of the user email
+// and group name is set.
+func policyMember(userEmail string, groupName string) (string, string, error) {
+	if userEmail != "" && groupName != "" {
+		return "", "", fmt.Errorf("both user email and group name cannot be provided")
+	}
+	if userEmail != "" {
+		return api.MemberTypeUser, userEmail, nil
+	}
+	if groupName != "" {
+		return api.MemberTypeGroup, groupName, nil
+	}
+	return "", "", fmt.Errorf("either user email or group name is required")
+}
+
+// State IDs written before the member type was recorded only carry the
+// member. The state stored along with them tells whether it is a user
+// or a group. Otherwise, e.g. on import, the member type stays empty
+// and is resolved from the policies on the server.
+func migratePolicyStateId(id api.PolicyStateId, userEmail string, groupName string) api.PolicyStateId {
+	if id.MemberType != "" {
+		return id
+	}
+
+	if id.Member == userEmail && id.Member != groupName {
+		id.MemberType = api.MemberTypeUser
+	} else if id.Member == groupName && id.Member != userEmail {
+		id.MemberType = api.MemberTypeGroup
+	}
+	return id
+}
+
+// Look up the policy of a state ID, the member type of legacy state IDs
+// is taken from the state or else from the server.
+func findPolicy(client *api.Client, kind api.PolicyKind, id api.PolicyStateId, userEmail string, groupName string) (api.Policy, error) {
+	return client.FindHypertablePolicy(kind, migratePolicyStateId(id, userEmail, groupName))
+}
+
+// Whether the policy, or the hypertable it belongs to, no longer exists.
+func policyNotFound(err error) bool {
+	return errors.Is(err, api.ErrNotFound) ||
+		err.Error() == "Not Found" || err.Error() == "404 Not Found"
+}
+
+// ID of the policy with the state ID, empty if there is none.
+func policyIdOf(client *api.Client, kind api.PolicyKind, id api.PolicyStateId) (string, error) {
+	policy, err := client.FindHypertablePolicy(kind, id)
+	if err != nil && policyNotFound(err) {
+		return "", nil
+	}
+	return policy.PolicyId, err
+}
+
+// Delete the policy an update created under the state ID, while it is
+// still the one created. Policies are deleted by state ID rather than
+// policy ID, which must not hit a policy of another resource.
+func rollbackPolicy(client *api.Client, kind api.PolicyKind, id api.PolicyStateId, createdId string, del func(api.PolicyStateId) error) error {
+	if createdId == "" {
+		return nil
+	}
+
+	currentId, err := policyIdOf(client, kind, id)
+	if err != nil || currentId != createdId {
+		return err
+	}
+	return del(id)
+}
+
+// Hypertable, member and column of a policy resource. The column is
+// empty for access control policies.
+type policyTarget struct {
+	HypertableId string
+	UserEmail    string
+	GroupName    string
+	Column       string
+}
+
+// Update a policy resource from its state to its plan. The server
+// creates or updates policies by hypertable, member and column.
+// Changing any of those creates a new policy, the existing one is
+// deleted only once its replacement is in place. If that fails, the
+// new policy is rolled back and the existing one kept.
+func updatePolicy(
+	client *api.Client, kind api.PolicyKind, existing api.PolicyStateId,
+	state policyTarget, plan policyTarget,
+	create func() *schema.ServiceResponse, del func(api.PolicyStateId) error,
+) *schema.ServiceResponse {
+	existing = migratePolicyStateId(existing, state.UserEmail, state.GroupName)
+	if existing.MemberType == "" {
+		return schema.ErrorResponse(fmt.Errorf(
+			"member type of %q is unknown, refresh the state first", existing.Member,
+		))
+	}
+
+	memberType, member, err := policyMember(plan.UserEmail, plan.GroupName)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	updated := api.PolicyStateId{
+		HypertableId: plan.HypertableId, MemberType: memberType, Member: member,
+		Column: plan.Column,
+	}
+	if updated == existing {
+		return create()
+	}
+
+	// If the member already has a policy of the kind, e.g. access to
+	// the hypertable, that policy is updated in place and not rolled
+	// back.
+	previousId, err := policyIdOf(client, kind, updated)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
+	res := create()
+	if res.ErrorsContents != "" {
+		return res
+	}
+
+	// Without its policy ID the new policy cannot be rolled back.
+	createdId := ""
+	var lookupErr error
+	if previousId == "" {
+		createdId, lookupErr = policyIdOf(client, kind, updated)
+	}
+
+	err = del(existing)
+	if err != nil {
+		// Keep the existing policy and drop the new one.
+		if lookupErr != nil {
+			return schema.ErrorResponse(fmt.Errorf(
+				"%s (rollback skipped, policy %s needs to be deleted manually: %s)",
+				err, res.StateID, lookupErr,
+			))
+		}
+		rbErr := rollbackPolicy(client, kind, updated, createdId, del)
+		if rbErr != nil {
+			return schema.ErrorResponse(fmt.Errorf(
+				"%s (rollback failed, policy %s needs to be deleted manually: %s)",
+				err, res.StateID, rbErr,
+			))
+		}
+		return schema.ErrorResponse(err)
+	}
+
+	return res
+}
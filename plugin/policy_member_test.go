@@ -0,0 +1,188 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zipstack/pct-plugin-framework/schema"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Access control of hypertable ht1 on a test server. Grants are keyed
+// by user email, deleting a member in failDelete fails, and reads fail
+// once failReads is set.
+type testAccessServer struct {
+	mu         sync.Mutex
+	users      map[string]string
+	nextId     int
+	failDelete map[string]bool
+	failReads  bool
+}
+
+func newTestAccessClient(t *testing.T, srv *testAccessServer) *api.Client {
+	t.Helper()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+
+		payload := api.HypertableAccessControl{}
+		if r.Body != nil {
+			json.NewDecoder(r.Body).Decode(&payload)
+		}
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/account/login"):
+			http.SetCookie(w, &http.Cookie{Name: "SESSION", Value: "session"})
+			http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: "token"})
+		case r.Method == "GET" && r.URL.Path == "/api/v1/access-control/access/ht1":
+			if srv.failReads {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"status":500,"error":"read failed"}`))
+				return
+			}
+			users := []map[string]string{}
+			for member, policyId := range srv.users {
+				users = append(users, map[string]string{"policyId": policyId, "member": member})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"hypertableId": "ht1", "users": users, "groups": []string{},
+			})
+		case r.Method == "POST" && r.URL.Path == "/api/v1/access-control/access":
+			if _, ok := srv.users[payload.UserEmail]; !ok {
+				srv.nextId++
+				srv.users[payload.UserEmail] = fmt.Sprintf("p%d", srv.nextId)
+			}
+			w.Write([]byte("true"))
+		case r.Method == "DELETE" && r.URL.Path == "/api/v1/access-control/access":
+			if srv.failDelete[payload.UserEmail] {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"status":500,"error":"delete failed"}`))
+				return
+			}
+			delete(srv.users, payload.UserEmail)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	// Accounts are unique per test, clients of an account share their
+	// read cache.
+	c, err := api.NewClient(s.URL, "org", t.Name()+"@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestUpdatePolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		users      map[string]string
+		failDelete []string
+		// Reads fail after the new grant is created.
+		failReadsAfterCreate bool
+		wantErr              string
+		wantUsers            []string
+	}{
+		{
+			name:      "replaced",
+			users:     map[string]string{"a": "p0"},
+			wantUsers: []string{"b"},
+		},
+		{
+			name:       "rolled back",
+			users:      map[string]string{"a": "p0"},
+			failDelete: []string{"a"},
+			wantErr:    "delete failed",
+			wantUsers:  []string{"a"},
+		},
+		{
+			name:       "existing grant kept",
+			users:      map[string]string{"a": "p0", "b": "p1"},
+			failDelete: []string{"a"},
+			wantErr:    "delete failed",
+			wantUsers:  []string{"a", "b"},
+		},
+		{
+			name:       "rollback failed",
+			users:      map[string]string{"a": "p0"},
+			failDelete: []string{"a", "b"},
+			wantErr:    "500 delete failed (rollback failed, policy v2:ht1:user:b needs to be deleted manually: 500 delete failed)",
+			wantUsers:  []string{"a", "b"},
+		},
+		{
+			name:                 "rollback skipped",
+			users:                map[string]string{"a": "p0"},
+			failDelete:           []string{"a"},
+			failReadsAfterCreate: true,
+			wantErr:              "500 delete failed (rollback skipped, policy v2:ht1:user:b needs to be deleted manually: 500 read failed)",
+			wantUsers:            []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &testAccessServer{users: tt.users, failDelete: map[string]bool{}}
+			for _, member := range tt.failDelete {
+				srv.failDelete[member] = true
+			}
+			client := newTestAccessClient(t, srv)
+			r := &hypertableAccessControlResource{Client: client}
+
+			create := func() *schema.ServiceResponse {
+				if !tt.failReadsAfterCreate {
+					return r.create(hypertableAccessControlResourceModel{
+						HypertableId: "ht1", UserEmail: "b",
+					})
+				}
+
+				_, err := client.CreateHypertableAccessControl(api.HypertableAccessControl{
+					HypertableId: "ht1", UserEmail: "b",
+				})
+				if err != nil {
+					return schema.ErrorResponse(err)
+				}
+				srv.mu.Lock()
+				srv.failReads = true
+				srv.mu.Unlock()
+				return &schema.ServiceResponse{
+					StateID: client.GetHypertableAccessControlStateId("ht1", api.MemberTypeUser, "b"),
+				}
+			}
+
+			res := updatePolicy(
+				client, api.PolicyKindAccessControl,
+				api.PolicyStateId{HypertableId: "ht1", MemberType: api.MemberTypeUser, Member: "a"},
+				policyTarget{UserEmail: "a"},
+				policyTarget{HypertableId: "ht1", UserEmail: "b"},
+				create, r.delete,
+			)
+
+			if tt.wantErr == "" && res.ErrorsContents != "" {
+				t.Fatalf("updatePolicy failed: %s", res.ErrorsContents)
+			}
+			if tt.wantErr != "" && !strings.Contains(res.ErrorsContents, tt.wantErr) {
+				t.Fatalf("updatePolicy error = %q, want %q", res.ErrorsContents, tt.wantErr)
+			}
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			if len(srv.users) != len(tt.wantUsers) {
+				t.Errorf("server has grants %v, want %v", srv.users, tt.wantUsers)
+			}
+			for _, member := range tt.wantUsers {
+				if _, ok := srv.users[member]; !ok {
+					t.Errorf("server has grants %v, want %v", srv.users, tt.wantUsers)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Entry of an authoritative policy resource, the value being the
+// masking option or the filter expression.
+type policyEntry struct {
+	Column     string
+	MemberType string
+	Member     string
+	Value      string
+}
+
+type policyEntryKey struct {
+	Column     string
+	MemberType string
+	Member     string
+}
+
+func (e policyEntry) key() policyEntryKey {
+	return policyEntryKey{Column: e.Column, MemberType: e.MemberType, Member: e.Member}
+}
+
+// Changes reconciling the entries on the server with the desired ones.
+// The server creates or updates entries by column and member, hence
+// changed values are applied in place. Unmanaged are the deleted
+// entries which were created out-of-band.
+type policyChanges struct {
+	Apply     []policyEntry
+	Delete    []policyEntry
+	Unmanaged []policyEntry
+}
+
+func diffPolicyEntries(desired []policyEntry, actual []policyEntry, managed []policyEntry) policyChanges {
+	changes := policyChanges{}
+
+	actualByKey := map[policyEntryKey]policyEntry{}
+	for _, e := range actual {
+		actualByKey[e.key()] = e
+	}
+	desiredKeys := map[policyEntryKey]bool{}
+	for _, e := range desired {
+		desiredKeys[e.key()] = true
+	}
+	managedKeys := map[policyEntryKey]bool{}
+	for _, e := range managed {
+		managedKeys[e.key()] = true
+	}
+
+	for _, e := range desired {
+		a, ok := actualByKey[e.key()]
+		if !ok || a.Value != e.Value {
+			changes.Apply = append(changes.Apply, e)
+		}
+	}
+	for _, e := range actual {
+		if desiredKeys[e.key()] {
+			continue
+		}
+		changes.Delete = append(changes.Delete, e)
+		if !managedKeys[e.key()] {
+			changes.Unmanaged = append(changes.Unmanaged, e)
+		}
+	}
+	return changes
+}
+
+// Leave entries created out-of-band in place, only the managed ones
+// are deleted.
+func (c policyChanges) keepUnmanaged() policyChanges {
+	unmanaged := map[policyEntryKey]bool{}
+	for _, e := range c.Unmanaged {
+		unmanaged[e.key()] = true
+	}
+
+	deletes := []policyEntry{}
+	for _, e := range c.Delete {
+		if !unmanaged[e.key()] {
+			deletes = append(deletes, e)
+		}
+	}
+	c.Delete = deletes
+	return c
+}
+
+func validatePolicyEntries(entries []policyEntry) error {
+	seen := map[policyEntryKey]bool{}
+	for _, e := range entries {
+		if e.Column == "" {
+			return fmt.Errorf("column is required")
+		}
+		if e.Member == "" {
+			return fmt.Errorf("member is required for column %q", e.Column)
+		}
+		if e.MemberType != api.MemberTypeUser && e.MemberType != api.MemberTypeGroup {
+			return fmt.Errorf(
+				"invalid member type %q of %q, expected %s or %s",
+				e.MemberType, e.Member, api.MemberTypeUser, api.MemberTypeGroup,
+			)
+		}
+		if seen[e.key()] {
+			return fmt.Errorf(
+				"duplicate entry for %s %q on column %q", e.MemberType, e.Member, e.Column,
+			)
+		}
+		seen[e.key()] = true
+	}
+	return nil
+}
+
+// Validate the columns of the entries against the hypertable schema,
+// which is skipped while the schema is unknown, i.e. empty.
+func validatePolicyEntryColumns(hypertableId string, columns []api.HypertableColumn, entries []policyEntry) error {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	exists := map[string]bool{}
+	for _, c := range columns {
+		exists[c.Name] = true
+	}
+	for _, e := range entries {
+		if !exists[e.Column] {
+			return fmt.Errorf(
+				"column %q does not exist in hypertable %s", e.Column, hypertableId,
+			)
+		}
+	}
+	return nil
+}
+
+// The actual entries in the configured order, entries missing in the
+// configuration are appended in the order of the server.
+func mergePolicyEntries(configured []policyEntry, actual []policyEntry) []policyEntry {
+	actualByKey := map[policyEntryKey]policyEntry{}
+	for _, e := range actual {
+		actualByKey[e.key()] = e
+	}
+	configuredKeys := map[policyEntryKey]bool{}
+	for _, e := range configured {
+		configuredKeys[e.key()] = true
+	}
+
+	entries := []policyEntry{}
+	for _, e := range configured {
+		if a, ok := actualByKey[e.key()]; ok {
+			entries = append(entries, a)
+		}
+	}
+	for _, e := range actual {
+		if !configuredKeys[e.key()] {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Split the entries into those configured and the others.
+func splitPolicyEntries(entries []policyEntry, configured []policyEntry) (managed []policyEntry, unmanaged []policyEntry) {
+	keys := map[policyEntryKey]bool{}
+	for _, e := range configured {
+		keys[e.key()] = true
+	}
+
+	managed, unmanaged = []policyEntry{}, []policyEntry{}
+	for _, e := range entries {
+		if keys[e.key()] {
+			managed = append(managed, e)
+		} else {
+			unmanaged = append(unmanaged, e)
+		}
+	}
+	return managed, unmanaged
+}
+
+func policyEntriesOf(policies []api.Policy) []policyEntry {
+	entries := []policyEntry{}
+	for _, p := range policies {
+		value := p.MaskingOption
+		if p.Kind == api.PolicyKindRowFilter {
+			value = p.FilterExpression
+		}
+
+		entries = append(entries, policyEntry{
+			Column: p.Column, MemberType: p.MemberType, Member: p.Member, Value: value,
+		})
+	}
+	return entries
+}
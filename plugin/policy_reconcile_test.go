@@ -0,0 +1,208 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+func testUserEntry(column string, member string, value string) policyEntry {
+	return policyEntry{Column: column, MemberType: api.MemberTypeUser, Member: member, Value: value}
+}
+
+func testGroupEntry(column string, member string, value string) policyEntry {
+	return policyEntry{Column: column, MemberType: api.MemberTypeGroup, Member: member, Value: value}
+}
+
+func TestDiffPolicyEntries(t *testing.T) {
+	tests := []struct {
+		name          string
+		desired       []policyEntry
+		actual        []policyEntry
+		managed       []policyEntry
+		keepUnmanaged bool
+		want          policyChanges
+	}{
+		{
+			name:    "unchanged",
+			desired: []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			actual:  []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			managed: []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			want:    policyChanges{},
+		},
+		{
+			name:    "added",
+			desired: []policyEntry{testUserEntry("email", "a", "EMAIL"), testGroupEntry("email", "b", "HASH")},
+			actual:  []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			managed: []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			want:    policyChanges{Apply: []policyEntry{testGroupEntry("email", "b", "HASH")}},
+		},
+		{
+			name:    "value changed in place",
+			desired: []policyEntry{testUserEntry("email", "a", "HASH")},
+			actual:  []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			managed: []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			want:    policyChanges{Apply: []policyEntry{testUserEntry("email", "a", "HASH")}},
+		},
+		{
+			name:    "member type is part of the key",
+			desired: []policyEntry{testGroupEntry("email", "a", "EMAIL")},
+			actual:  []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			managed: []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			want: policyChanges{
+				Apply:  []policyEntry{testGroupEntry("email", "a", "EMAIL")},
+				Delete: []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			},
+		},
+		{
+			name:    "managed removed",
+			desired: []policyEntry{},
+			actual:  []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			managed: []policyEntry{testUserEntry("email", "a", "HASH")},
+			want:    policyChanges{Delete: []policyEntry{testUserEntry("email", "a", "EMAIL")}},
+		},
+		{
+			name:    "out-of-band removed",
+			desired: []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			actual:  []policyEntry{testUserEntry("email", "a", "EMAIL"), testUserEntry("phone", "a", "HASH")},
+			managed: []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			want: policyChanges{
+				Delete:    []policyEntry{testUserEntry("phone", "a", "HASH")},
+				Unmanaged: []policyEntry{testUserEntry("phone", "a", "HASH")},
+			},
+		},
+		{
+			name:    "everything out-of-band without previous apply",
+			desired: []policyEntry{},
+			actual:  []policyEntry{testUserEntry("email", "a", "EMAIL"), testGroupEntry("email", "b", "HASH")},
+			managed: nil,
+			want: policyChanges{
+				Delete:    []policyEntry{testUserEntry("email", "a", "EMAIL"), testGroupEntry("email", "b", "HASH")},
+				Unmanaged: []policyEntry{testUserEntry("email", "a", "EMAIL"), testGroupEntry("email", "b", "HASH")},
+			},
+		},
+		{
+			name:          "out-of-band kept",
+			desired:       []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			actual:        []policyEntry{testUserEntry("email", "a", "EMAIL"), testUserEntry("phone", "a", "HASH")},
+			managed:       []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			keepUnmanaged: true,
+			want: policyChanges{
+				Delete:    []policyEntry{},
+				Unmanaged: []policyEntry{testUserEntry("phone", "a", "HASH")},
+			},
+		},
+		{
+			name:          "managed removed with out-of-band kept",
+			desired:       []policyEntry{},
+			actual:        []policyEntry{testUserEntry("email", "a", "EMAIL"), testGroupEntry("phone", "b", "HASH")},
+			managed:       []policyEntry{testUserEntry("email", "a", "EMAIL")},
+			keepUnmanaged: true,
+			want: policyChanges{
+				Delete:    []policyEntry{testUserEntry("email", "a", "EMAIL")},
+				Unmanaged: []policyEntry{testGroupEntry("phone", "b", "HASH")},
+			},
+		},
+		{
+			name:          "access entries",
+			desired:       []policyEntry{{MemberType: api.MemberTypeUser, Member: "a"}},
+			actual:        []policyEntry{{MemberType: api.MemberTypeGroup, Member: "b"}},
+			managed:       []policyEntry{{MemberType: api.MemberTypeGroup, Member: "b"}},
+			keepUnmanaged: true,
+			want: policyChanges{
+				Apply:  []policyEntry{{MemberType: api.MemberTypeUser, Member: "a"}},
+				Delete: []policyEntry{{MemberType: api.MemberTypeGroup, Member: "b"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffPolicyEntries(tt.desired, tt.actual, tt.managed)
+			if tt.keepUnmanaged {
+				got = got.keepUnmanaged()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffPolicyEntries =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePolicyEntries(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured []policyEntry
+		actual     []policyEntry
+		want       []policyEntry
+	}{
+		{
+			name:       "configured order",
+			configured: []policyEntry{testUserEntry("b", "a", "X"), testUserEntry("a", "a", "X")},
+			actual:     []policyEntry{testUserEntry("a", "a", "X"), testUserEntry("b", "a", "X")},
+			want:       []policyEntry{testUserEntry("b", "a", "X"), testUserEntry("a", "a", "X")},
+		},
+		{
+			name:       "actual values",
+			configured: []policyEntry{testUserEntry("a", "a", "X")},
+			actual:     []policyEntry{testUserEntry("a", "a", "Y")},
+			want:       []policyEntry{testUserEntry("a", "a", "Y")},
+		},
+		{
+			name:       "removed out-of-band",
+			configured: []policyEntry{testUserEntry("a", "a", "X"), testUserEntry("b", "a", "X")},
+			actual:     []policyEntry{testUserEntry("b", "a", "X")},
+			want:       []policyEntry{testUserEntry("b", "a", "X")},
+		},
+		{
+			name:       "added out-of-band",
+			configured: []policyEntry{testUserEntry("a", "a", "X")},
+			actual: []policyEntry{
+				testGroupEntry("c", "g", "X"), testUserEntry("a", "a", "X"), testGroupEntry("b", "g", "X"),
+			},
+			want: []policyEntry{
+				testUserEntry("a", "a", "X"), testGroupEntry("c", "g", "X"), testGroupEntry("b", "g", "X"),
+			},
+		},
+		{
+			name:       "nothing on the server",
+			configured: []policyEntry{testUserEntry("a", "a", "X")},
+			actual:     nil,
+			want:       []policyEntry{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePolicyEntries(tt.configured, tt.actual); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePolicyEntries =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitPolicyEntries(t *testing.T) {
+	entries := []policyEntry{
+		testUserEntry("a", "a", "X"), testGroupEntry("a", "a", "X"),
+		testUserEntry("b", "a", "Y"), testUserEntry("c", "a", "X"),
+	}
+	// Entries are matched by column and member, not by value.
+	configured := []policyEntry{testUserEntry("b", "a", "X"), testUserEntry("a", "a", "X")}
+
+	managed, unmanaged := splitPolicyEntries(entries, configured)
+
+	wantManaged := []policyEntry{testUserEntry("a", "a", "X"), testUserEntry("b", "a", "Y")}
+	wantUnmanaged := []policyEntry{testGroupEntry("a", "a", "X"), testUserEntry("c", "a", "X")}
+	if !reflect.DeepEqual(managed, wantManaged) {
+		t.Errorf("managed =\n%+v\nwant\n%+v", managed, wantManaged)
+	}
+	if !reflect.DeepEqual(unmanaged, wantUnmanaged) {
+		t.Errorf("unmanaged =\n%+v\nwant\n%+v", unmanaged, wantUnmanaged)
+	}
+
+	managed, unmanaged = splitPolicyEntries(nil, configured)
+	if len(managed) != 0 || len(unmanaged) != 0 || managed == nil || unmanaged == nil {
+		t.Errorf("splitPolicyEntries(nil) = %#v, %#v, want empty lists", managed, unmanaged)
+	}
+}
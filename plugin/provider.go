@@ -2,6 +2,8 @@ package plugin
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/zipstack/pct-plugin-framework/fwhelpers"
 	"github.com/zipstack/pct-plugin-framework/schema"
@@ -21,6 +23,11 @@ type ProviderModel struct {
 	OrganisationName string `pctsdk:"organisationname"`
 	Email            string `pctsdk:"email"`
 	Password         string `pctsdk:"password"`
+
+	MinScheduleInterval int64    `pctsdk:"min_schedule_interval"`
+	RowFilterOperators  []string `pctsdk:"row_filter_operators"`
+	RowFilterFunctions  []string `pctsdk:"row_filter_functions"`
+	SQLFileDir          string   `pctsdk:"sql_file_dir"`
 }
 
 // Ensure the implementation satisfies the expected interfaces
@@ -62,6 +69,39 @@ func (p *Provider) Schema() *schema.ServiceResponse {
 				Required:    true,
 				Sensitive:   true,
 			},
+			"min_schedule_interval": &schema.IntAttribute{
+				Description: "Minimum interval in seconds between scheduled " +
+					"hypertable runs (default 60), checked on apply before any change is made",
+				Required: true,
+				Optional: true,
+			},
+			"row_filter_operators": &schema.ListAttribute{
+				Description: "Operators allowed in row filter SQL conditions " +
+					"(default all supported), checked on apply before any change is made",
+				Required: true,
+				Optional: true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "Operator",
+					Required:    true,
+				},
+			},
+			"row_filter_functions": &schema.ListAttribute{
+				Description: "Functions allowed in row filter SQL conditions " +
+					"(default LOWER, UPPER, TRIM, LENGTH, COALESCE, CURRENT_DATE, " +
+					"CURRENT_TIMESTAMP, CURRENT_USER), checked on apply before any change is made",
+				Required: true,
+				Optional: true,
+				NestedAttribute: &schema.StringAttribute{
+					Description: "Function",
+					Required:    true,
+				},
+			},
+			"sql_file_dir": &schema.StringAttribute{
+				Description: "Directory relative sql_file and query_file paths are resolved " +
+					"against (default the working directory of the provider plugin process)",
+				Required: true,
+				Optional: true,
+			},
 		},
 	}
 
@@ -90,6 +130,11 @@ func (p *Provider) Configure(req *schema.ServiceRequest) *schema.ServiceResponse
 		))
 	}
 
+	_, err = newRowFilterAllowlist(pm.RowFilterOperators, pm.RowFilterFunctions)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+
 	if p.Client == nil {
 		client, err := api.NewClient(
 			pm.Host, pm.OrganisationName,
@@ -101,12 +146,18 @@ func (p *Provider) Configure(req *schema.ServiceRequest) *schema.ServiceResponse
 		p.Client = client
 	}
 
-	// Make API creds available for Resource type Configure methods.
+	// Make API creds and settings available for Resource type
+	// Configure methods.
 	creds := map[string]string{
 		"host":             pm.Host,
 		"organisationname": pm.OrganisationName,
 		"email":            pm.Email,
 		"password":         pm.Password,
+
+		"min_schedule_interval": strconv.FormatInt(pm.MinScheduleInterval, 10),
+		"row_filter_operators":  strings.Join(pm.RowFilterOperators, ","),
+		"row_filter_functions":  strings.Join(pm.RowFilterFunctions, ","),
+		"sql_file_dir":          pm.SQLFileDir,
 	}
 	cEnc, err := fwhelpers.Encode(creds)
 	if err != nil {
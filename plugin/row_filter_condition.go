@@ -0,0 +1,791 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Row filter conditions are parsed before any change is made. The
+// dialect has comparisons, IN, BETWEEN, LIKE and IS NULL predicates
+// combined by AND, OR and NOT, arithmetic and function calls.
+// Columns may be qualified, e.g. "t.region", and LIKE may have an
+// ESCAPE. Subqueries, comments and multiple statements are rejected,
+// as are conditions which are always true or always false: without
+// any column reference, e.g. "OR 1=1", comparing a column with itself,
+// e.g. "OR region = region", or testing a predicate and its negation,
+// e.g. "x IS NULL OR x IS NOT NULL".
+
+// Operators supported by the parser, all allowed by default.
+var rowFilterOperators = []string{
+	"=", "!=", "<>", "<", "<=", ">", ">=",
+	"AND", "OR", "NOT", "IN", "BETWEEN", "LIKE", "IS",
+	"+", "-", "*", "/", "%", "||",
+}
+
+// Functions which may be allowed, called with comma separated
+// arguments.
+var rowFilterFunctions = []string{
+	"LOWER", "UPPER", "TRIM", "LTRIM", "RTRIM", "LENGTH", "SUBSTR", "SUBSTRING",
+	"CONCAT", "REPLACE", "COALESCE", "NULLIF", "ABS", "ROUND", "FLOOR", "CEIL",
+	"MOD", "DATE_TRUNC", "NOW", "CURRENT_DATE", "CURRENT_TIMESTAMP", "CURRENT_USER",
+}
+
+// Functions allowed by default.
+var defaultRowFilterFunctions = []string{
+	"LOWER", "UPPER", "TRIM", "LENGTH", "COALESCE",
+	"CURRENT_DATE", "CURRENT_TIMESTAMP", "CURRENT_USER",
+}
+
+// Functions which are called without parentheses.
+var niladicRowFilterFunctions = map[string]bool{
+	"CURRENT_DATE":      true,
+	"CURRENT_TIMESTAMP": true,
+	"CURRENT_USER":      true,
+}
+
+var rowFilterKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IN": true,
+	"BETWEEN": true, "LIKE": true, "IS": true,
+}
+
+type rowFilterAllowlist struct {
+	Operators map[string]bool
+	Functions map[string]bool
+}
+
+// Allowlist of the configured operators and functions, the defaults
+// apply to whichever is empty. Blank items, e.g. from a trailing
+// comma, are skipped.
+func newRowFilterAllowlist(operators []string, functions []string) (rowFilterAllowlist, error) {
+	allow := rowFilterAllowlist{
+		Operators: map[string]bool{},
+		Functions: map[string]bool{},
+	}
+
+	operators = allowlistItems(operators)
+	if len(operators) == 0 {
+		operators = rowFilterOperators
+	}
+	for _, op := range operators {
+		if !containsMember(rowFilterOperators, op) {
+			return allow, fmt.Errorf(
+				"unsupported row filter operator %q, expected one of %s",
+				op, strings.Join(rowFilterOperators, " "),
+			)
+		}
+		allow.Operators[op] = true
+	}
+
+	functions = allowlistItems(functions)
+	if len(functions) == 0 {
+		functions = defaultRowFilterFunctions
+	}
+	for _, fn := range functions {
+		if !containsMember(rowFilterFunctions, fn) {
+			return allow, fmt.Errorf(
+				"unsupported row filter function %q, expected one of %s",
+				fn, strings.Join(rowFilterFunctions, " "),
+			)
+		}
+		allow.Functions[fn] = true
+	}
+
+	return allow, nil
+}
+
+// Upper case items of an allowlist, without the blank ones.
+func allowlistItems(items []string) []string {
+	upper := []string{}
+	for _, item := range items {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if item != "" {
+			upper = append(upper, item)
+		}
+	}
+	return upper
+}
+
+// The allowlist is passed to the resources along with the credentials,
+// as comma separated lists.
+func rowFilterAllowlistOf(creds map[string]string) (rowFilterAllowlist, error) {
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, ",")
+	}
+	return newRowFilterAllowlist(
+		split(creds["row_filter_operators"]), split(creds["row_filter_functions"]),
+	)
+}
+
+// Validate the condition, the column references against the columns
+// of the hypertable unless its schema is unknown, i.e. empty.
+func validateRowFilterCondition(condition string, source string, columns []api.HypertableColumn, allow rowFilterAllowlist) error {
+	errs := parseRowFilterCondition(condition, columns, allow)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	msgs := []string{}
+	for _, e := range errs {
+		line, column := conditionPosition(condition, e.Pos)
+		msgs = append(msgs, fmt.Sprintf("  line %d, column %d: %s", line, column, e.Msg))
+	}
+	return fmt.Errorf("invalid SQL condition in %s:\n%s", source, strings.Join(msgs, "\n"))
+}
+
+// Validate the condition against the current columns of the hypertable.
+func validateHypertableRowFilterCondition(client *api.Client, hypertableId string, condition string, source string, allow rowFilterAllowlist) error {
+	columns, err := client.ReadHypertableColumns(hypertableId)
+	if err != nil {
+		return fmt.Errorf("failed to read hypertable columns: %s", err)
+	}
+	return validateRowFilterCondition(condition, source, columns, allow)
+}
+
+const (
+	conditionEOF = iota
+	conditionIdent
+	conditionQuotedIdent
+	conditionNumber
+	conditionString
+	conditionOperator
+	conditionLParen
+	conditionRParen
+	conditionComma
+	conditionDot
+)
+
+type conditionToken struct {
+	Kind int
+	Text string
+	Pos  int
+}
+
+// Error at a byte offset of the condition.
+type conditionError struct {
+	Pos int
+	Msg string
+}
+
+// 1-based line and column of a byte offset.
+func conditionPosition(src string, pos int) (int, int) {
+	line, column := 1, 1
+	for _, c := range src[:pos] {
+		if c == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+func lexCondition(src string) ([]conditionToken, *conditionError) {
+	tokens := []conditionToken{}
+
+	i := 0
+	for i < len(src) {
+		c, size := utf8.DecodeRuneInString(src[i:])
+		rest := src[i:]
+
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+
+		case c == ';':
+			return nil, &conditionError{i, "multiple statements are not allowed"}
+
+		case strings.HasPrefix(rest, "--") || strings.HasPrefix(rest, "/*"):
+			return nil, &conditionError{i, "comments are not allowed"}
+
+		case c == '\'':
+			j := i + 1
+			for {
+				if j >= len(src) {
+					return nil, &conditionError{i, "unterminated string"}
+				}
+				if src[j] == '\'' {
+					if j+1 < len(src) && src[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, conditionToken{conditionString, src[i : j+1], i})
+			i = j + 1
+
+		case c == '"' || c == '`':
+			quote := src[i]
+			j := i + 1
+			name := strings.Builder{}
+			for {
+				if j >= len(src) {
+					return nil, &conditionError{i, "unterminated identifier"}
+				}
+				if src[j] == quote {
+					if j+1 < len(src) && src[j+1] == quote {
+						name.WriteByte(quote)
+						j += 2
+						continue
+					}
+					break
+				}
+				name.WriteByte(src[j])
+				j++
+			}
+			if name.Len() == 0 {
+				return nil, &conditionError{i, "empty identifier"}
+			}
+			tokens = append(tokens, conditionToken{conditionQuotedIdent, name.String(), i})
+			i = j + 1
+
+		case unicode.IsDigit(c) || (c == '.' && len(rest) > 1 && unicode.IsDigit(rune(rest[1]))):
+			j := i
+			dots := 0
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				if src[j] == '.' {
+					dots++
+				}
+				j++
+			}
+			// Exponent, e.g. 1e5 or 1.5E-3.
+			if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+				k := j + 1
+				if k < len(src) && (src[k] == '+' || src[k] == '-') {
+					k++
+				}
+				if k < len(src) && unicode.IsDigit(rune(src[k])) {
+					j = k
+					for j < len(src) && unicode.IsDigit(rune(src[j])) {
+						j++
+					}
+				}
+			}
+			end := j
+			for end < len(src) {
+				r, n := utf8.DecodeRuneInString(src[end:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+					break
+				}
+				end += n
+			}
+			if dots > 1 || end > j {
+				return nil, &conditionError{i, fmt.Sprintf("invalid number %q", src[i:end])}
+			}
+			tokens = append(tokens, conditionToken{conditionNumber, src[i:j], i})
+			i = j
+
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(src) {
+				r, n := utf8.DecodeRuneInString(src[j:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+					break
+				}
+				j += n
+			}
+			tokens = append(tokens, conditionToken{conditionIdent, src[i:j], i})
+			i = j
+
+		case c == '(':
+			tokens = append(tokens, conditionToken{conditionLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, conditionToken{conditionRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, conditionToken{conditionComma, ",", i})
+			i++
+		case c == '.':
+			tokens = append(tokens, conditionToken{conditionDot, ".", i})
+			i++
+
+		default:
+			op := ""
+			for _, candidate := range []string{"<=", ">=", "<>", "!=", "||", "=", "<", ">", "+", "-", "*", "/", "%"} {
+				if strings.HasPrefix(rest, candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &conditionError{i, fmt.Sprintf("unexpected character %q", c)}
+			}
+			tokens = append(tokens, conditionToken{conditionOperator, op, i})
+			i += len(op)
+		}
+	}
+
+	tokens = append(tokens, conditionToken{conditionEOF, "", len(src)})
+	return tokens, nil
+}
+
+// Recursive descent parser. Syntax errors stop the parser, while the
+// other errors are collected.
+type conditionParser struct {
+	tokens  []conditionToken
+	i       int
+	columns []api.HypertableColumn
+	allow   rowFilterAllowlist
+	errs    []conditionError
+}
+
+// Parsed expression, in a canonical text to compare expressions, e.g.
+// the operands of a comparison. Predicates negated by NOT, or by the
+// operator, e.g. <> and IS NOT NULL, have the text of the positive
+// predicate. Constant is why the expression is always true or always
+// false although it references a column.
+type conditionExpr struct {
+	Column   bool
+	Text     string
+	Negated  bool
+	Constant string
+}
+
+func (e conditionExpr) String() string {
+	if e.Negated {
+		return "NOT " + e.Text
+	}
+	return e.Text
+}
+
+// Comparison operators negating =, < and >.
+var negatedComparisons = map[string]string{
+	"!=": "=", "<>": "=", ">=": "<", "<=": ">",
+}
+
+func parseRowFilterCondition(condition string, columns []api.HypertableColumn, allow rowFilterAllowlist) []conditionError {
+	tokens, lexErr := lexCondition(condition)
+	if lexErr != nil {
+		return []conditionError{*lexErr}
+	}
+
+	p := &conditionParser{tokens: tokens, columns: columns, allow: allow}
+	if p.peek().Kind == conditionEOF {
+		return []conditionError{{0, "condition is empty"}}
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return append(p.errs, *err)
+	}
+	if t := p.peek(); t.Kind != conditionEOF {
+		return append(p.errs, conditionError{t.Pos, fmt.Sprintf("unexpected %q", t.Text)})
+	}
+	p.checkConstant(0, expr)
+
+	return p.errs
+}
+
+func (p *conditionParser) peek() conditionToken {
+	return p.tokens[p.i]
+}
+
+func (p *conditionParser) next() conditionToken {
+	t := p.tokens[p.i]
+	if t.Kind != conditionEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *conditionParser) keyword(kw string) bool {
+	t := p.peek()
+	return t.Kind == conditionIdent && strings.ToUpper(t.Text) == kw
+}
+
+func (p *conditionParser) operator(ops ...string) bool {
+	t := p.peek()
+	return t.Kind == conditionOperator && containsMember(ops, t.Text)
+}
+
+func (p *conditionParser) expect(kind int, text string) *conditionError {
+	t := p.next()
+	if t.Kind != kind {
+		return p.unexpected(t, fmt.Sprintf("expected %q", text))
+	}
+	return nil
+}
+
+func (p *conditionParser) unexpected(t conditionToken, expected string) *conditionError {
+	if t.Kind == conditionEOF {
+		return &conditionError{t.Pos, "unexpected end of condition, " + expected}
+	}
+	return &conditionError{t.Pos, fmt.Sprintf("unexpected %q, %s", t.Text, expected)}
+}
+
+func (p *conditionParser) useOperator(t conditionToken, op string) {
+	if !p.allow.Operators[op] {
+		p.errs = append(p.errs, conditionError{t.Pos, fmt.Sprintf("operator %s is not allowed", op)})
+	}
+}
+
+func (p *conditionParser) useFunction(t conditionToken) {
+	name := strings.ToUpper(t.Text)
+	if !p.allow.Functions[name] {
+		p.errs = append(p.errs, conditionError{t.Pos, fmt.Sprintf("function %s is not allowed", name)})
+	}
+}
+
+// Check the column exists and return its name.
+func (p *conditionParser) useColumn(t conditionToken) string {
+	if len(p.columns) == 0 {
+		return identifierName(t)
+	}
+	for _, c := range p.columns {
+		if c.Name == t.Text || (t.Kind == conditionIdent && strings.EqualFold(c.Name, t.Text)) {
+			return c.Name
+		}
+	}
+	p.errs = append(p.errs, conditionError{t.Pos, fmt.Sprintf("column %q does not exist", t.Text)})
+	return identifierName(t)
+}
+
+// Name of an identifier, unquoted ones being case insensitive.
+func identifierName(t conditionToken) string {
+	if t.Kind == conditionQuotedIdent {
+		return t.Text
+	}
+	return strings.ToLower(t.Text)
+}
+
+func (p *conditionParser) checkConstant(pos int, e conditionExpr) {
+	if !e.Column {
+		p.constant(pos, "references no column")
+	} else if e.Constant != "" {
+		p.constant(pos, e.Constant)
+	}
+}
+
+func (p *conditionParser) constant(pos int, reason string) {
+	p.errs = append(p.errs, conditionError{
+		pos, "condition " + reason + ", it is always true or always false",
+	})
+}
+
+// Operands of AND and OR are checked one by one, hence a chain counts
+// as referencing a column.
+func (p *conditionParser) parseOr() (conditionExpr, *conditionError) {
+	return p.parseLogical("OR", p.parseAnd)
+}
+
+func (p *conditionParser) parseAnd() (conditionExpr, *conditionError) {
+	return p.parseLogical("AND", p.parseNot)
+}
+
+func (p *conditionParser) parseLogical(op string, operand func() (conditionExpr, *conditionError)) (conditionExpr, *conditionError) {
+	pos := p.peek().Pos
+	e, err := operand()
+	if err != nil || !p.keyword(op) {
+		return e, err
+	}
+
+	// A predicate and its negation make an OR chain always true, and
+	// an AND chain always false.
+	negated := map[string]bool{}
+	texts := []string{}
+	for {
+		p.checkConstant(pos, e)
+		if n, ok := negated[e.Text]; ok && n != e.Negated {
+			p.constant(pos, fmt.Sprintf("tests both %s and its negation", e.Text))
+		}
+		negated[e.Text] = e.Negated
+		texts = append(texts, e.String())
+
+		if !p.keyword(op) {
+			break
+		}
+		p.useOperator(p.next(), op)
+
+		pos = p.peek().Pos
+		e, err = operand()
+		if err != nil {
+			return conditionExpr{}, err
+		}
+	}
+	return conditionExpr{Column: true, Text: strings.Join(texts, " "+op+" ")}, nil
+}
+
+func (p *conditionParser) parseNot() (conditionExpr, *conditionError) {
+	if p.keyword("NOT") {
+		p.useOperator(p.next(), "NOT")
+		e, err := p.parseNot()
+		e.Negated = !e.Negated
+		return e, err
+	}
+	return p.parsePredicate()
+}
+
+func (p *conditionParser) parsePredicate() (conditionExpr, *conditionError) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return conditionExpr{}, err
+	}
+
+	if p.operator("=", "!=", "<>", "<", "<=", ">", ">=") {
+		t := p.next()
+		p.useOperator(t, t.Text)
+
+		right, err := p.parseAdditive()
+		if err != nil {
+			return conditionExpr{}, err
+		}
+
+		e := conditionExpr{Column: left.Column || right.Column}
+		op := t.Text
+		if positive, ok := negatedComparisons[op]; ok {
+			op, e.Negated = positive, true
+		}
+		e.Text = left.String() + " " + op + " " + right.String()
+		if left.Column && left.String() == right.String() {
+			e.Constant = fmt.Sprintf("compares %s with itself", left.String())
+		}
+		return e, nil
+	}
+
+	if p.keyword("IS") {
+		p.useOperator(p.next(), "IS")
+		e := conditionExpr{Column: left.Column, Text: left.String() + " IS NULL"}
+		if p.keyword("NOT") {
+			p.useOperator(p.next(), "NOT")
+			e.Negated = true
+		}
+		if !p.keyword("NULL") {
+			return conditionExpr{}, p.unexpected(p.peek(), "expected NULL")
+		}
+		p.next()
+		return e, nil
+	}
+
+	negated := false
+	if p.keyword("NOT") {
+		t := p.next()
+		if !p.keyword("IN") && !p.keyword("BETWEEN") && !p.keyword("LIKE") {
+			return conditionExpr{}, p.unexpected(p.peek(), "expected IN, BETWEEN or LIKE")
+		}
+		p.useOperator(t, "NOT")
+		negated = true
+	}
+
+	switch {
+	case p.keyword("IN"):
+		p.useOperator(p.next(), "IN")
+		err = p.expect(conditionLParen, "(")
+		if err != nil {
+			return conditionExpr{}, err
+		}
+		if p.keyword("SELECT") {
+			return conditionExpr{}, &conditionError{p.peek().Pos, "subqueries are not allowed"}
+		}
+
+		e := conditionExpr{Column: left.Column, Negated: negated}
+		items := []string{}
+		for {
+			item, err := p.parseAdditive()
+			if err != nil {
+				return conditionExpr{}, err
+			}
+			e.Column = e.Column || item.Column
+			if left.Column && left.String() == item.String() {
+				e.Constant = fmt.Sprintf("compares %s with itself", left.String())
+			}
+			items = append(items, item.String())
+
+			if p.peek().Kind != conditionComma {
+				break
+			}
+			p.next()
+		}
+		e.Text = left.String() + " IN (" + strings.Join(items, ", ") + ")"
+		return e, p.expect(conditionRParen, ")")
+
+	case p.keyword("BETWEEN"):
+		p.useOperator(p.next(), "BETWEEN")
+		low, err := p.parseAdditive()
+		if err != nil {
+			return conditionExpr{}, err
+		}
+		if !p.keyword("AND") {
+			return conditionExpr{}, p.unexpected(p.peek(), "expected AND")
+		}
+		p.next()
+		high, err := p.parseAdditive()
+		return conditionExpr{
+			Column:  left.Column || low.Column || high.Column,
+			Text:    left.String() + " BETWEEN " + low.String() + " AND " + high.String(),
+			Negated: negated,
+		}, err
+
+	case p.keyword("LIKE"):
+		p.useOperator(p.next(), "LIKE")
+		pattern, err := p.parseAdditive()
+		if err != nil {
+			return conditionExpr{}, err
+		}
+		e := conditionExpr{
+			Column:  left.Column || pattern.Column,
+			Text:    left.String() + " LIKE " + pattern.String(),
+			Negated: negated,
+		}
+
+		// The escape character is a string of at most one character,
+		// the empty string disabling escapes.
+		if p.keyword("ESCAPE") {
+			p.next()
+			t := p.next()
+			if t.Kind != conditionString {
+				return conditionExpr{}, p.unexpected(t, "expected an escape string")
+			}
+			escape := strings.ReplaceAll(t.Text[1:len(t.Text)-1], "''", "'")
+			if utf8.RuneCountInString(escape) > 1 {
+				p.errs = append(p.errs, conditionError{t.Pos, "escape string must be a single character"})
+			}
+			e.Text += " ESCAPE " + t.Text
+		}
+		return e, nil
+	}
+
+	return left, nil
+}
+
+func (p *conditionParser) parseAdditive() (conditionExpr, *conditionError) {
+	left, err := p.parseMultiplicative()
+	for err == nil && p.operator("+", "-", "||") {
+		t := p.next()
+		p.useOperator(t, t.Text)
+
+		var right conditionExpr
+		right, err = p.parseMultiplicative()
+		left = conditionExpr{
+			Column: left.Column || right.Column,
+			Text:   left.String() + " " + t.Text + " " + right.String(),
+		}
+	}
+	return left, err
+}
+
+func (p *conditionParser) parseMultiplicative() (conditionExpr, *conditionError) {
+	left, err := p.parseUnary()
+	for err == nil && p.operator("*", "/", "%") {
+		t := p.next()
+		p.useOperator(t, t.Text)
+
+		var right conditionExpr
+		right, err = p.parseUnary()
+		left = conditionExpr{
+			Column: left.Column || right.Column,
+			Text:   left.String() + " " + t.Text + " " + right.String(),
+		}
+	}
+	return left, err
+}
+
+func (p *conditionParser) parseUnary() (conditionExpr, *conditionError) {
+	if p.operator("-", "+") {
+		t := p.next()
+		p.useOperator(t, t.Text)
+
+		e, err := p.parseUnary()
+		return conditionExpr{Column: e.Column, Text: t.Text + e.String()}, err
+	}
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parsePrimary() (conditionExpr, *conditionError) {
+	t := p.next()
+
+	switch t.Kind {
+	case conditionNumber, conditionString:
+		return conditionExpr{Text: t.Text}, nil
+
+	case conditionLParen:
+		if p.keyword("SELECT") {
+			return conditionExpr{}, &conditionError{p.peek().Pos, "subqueries are not allowed"}
+		}
+		e, err := p.parseOr()
+		if err != nil {
+			return conditionExpr{}, err
+		}
+		return e, p.expect(conditionRParen, ")")
+
+	case conditionQuotedIdent:
+		return p.parseColumn(t)
+
+	case conditionIdent:
+		name := strings.ToUpper(t.Text)
+		switch {
+		case name == "TRUE" || name == "FALSE" || name == "NULL":
+			return conditionExpr{Text: name}, nil
+		case name == "SELECT" || name == "EXISTS":
+			return conditionExpr{}, &conditionError{t.Pos, "subqueries are not allowed"}
+		case rowFilterKeywords[name]:
+			return conditionExpr{}, p.unexpected(t, "expected an operand")
+		}
+
+		if p.peek().Kind == conditionLParen {
+			p.useFunction(t)
+			p.next()
+
+			e := conditionExpr{}
+			args := []string{}
+			if p.peek().Kind != conditionRParen {
+				for {
+					arg, err := p.parseOr()
+					if err != nil {
+						return conditionExpr{}, err
+					}
+					e.Column = e.Column || arg.Column
+					args = append(args, arg.String())
+
+					if p.peek().Kind != conditionComma {
+						break
+					}
+					p.next()
+				}
+			}
+			e.Text = name + "(" + strings.Join(args, ", ") + ")"
+			return e, p.expect(conditionRParen, ")")
+		}
+
+		if niladicRowFilterFunctions[name] {
+			p.useFunction(t)
+			return conditionExpr{Text: name}, nil
+		}
+
+		return p.parseColumn(t)
+	}
+
+	return conditionExpr{}, p.unexpected(t, "expected an operand")
+}
+
+// Column reference, optionally qualified by the table, and schema,
+// names. Only the column itself is checked.
+func (p *conditionParser) parseColumn(t conditionToken) (conditionExpr, *conditionError) {
+	parts := []conditionToken{t}
+	for p.peek().Kind == conditionDot {
+		p.next()
+		part := p.next()
+		if part.Kind != conditionIdent && part.Kind != conditionQuotedIdent {
+			return conditionExpr{}, p.unexpected(part, "expected a name")
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) > 3 {
+		return conditionExpr{}, &conditionError{t.Pos, "column name has too many qualifiers"}
+	}
+
+	names := []string{}
+	for _, part := range parts[:len(parts)-1] {
+		names = append(names, identifierName(part))
+	}
+	names = append(names, p.useColumn(parts[len(parts)-1]))
+	return conditionExpr{Column: true, Text: strings.Join(names, ".")}, nil
+}
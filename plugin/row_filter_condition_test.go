@@ -0,0 +1,271 @@
+package plugin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+var testConditionColumns = []api.HypertableColumn{
+	{Name: "region"}, {Name: "amount"}, {Name: "name"}, {Name: "x"}, {Name: "Created"},
+}
+
+func testConditionAllowlist(t *testing.T) rowFilterAllowlist {
+	allow, err := newRowFilterAllowlist(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return allow
+}
+
+func TestParseRowFilterCondition(t *testing.T) {
+	allow := testConditionAllowlist(t)
+
+	tests := []string{
+		"region = 'EU'",
+		"region <> 'EU' AND amount > 100",
+		"REGION = 'EU' OR (amount BETWEEN 1 AND 10)",
+		"region IN ('EU', 'US') AND NOT amount < 0",
+		"region NOT IN ('EU')",
+		"name LIKE 'a%'",
+		"name NOT LIKE 'a!%%' ESCAPE '!'",
+		"name LIKE 'a%' ESCAPE ''",
+		"x IS NULL",
+		"x IS NOT NULL AND region = 'EU'",
+		"LOWER(name) = 'bob' OR COALESCE(amount, 0) > 1",
+		"\"Created\" < CURRENT_TIMESTAMP",
+		"t.region = 'EU'",
+		"\"t\".\"region\" = 'EU'",
+		"public.t.region = 'EU'",
+		"t.region = region",
+		"amount > 1e5",
+		"amount < 1.5E-3 OR amount > .5e+2",
+		"amount * 2 + 1 >= 10 % 3",
+		"name || 'x' = 'bobx'",
+		"-amount < 0",
+		"region = 'it''s'",
+		"region = 'EU' OR region = 'US'",
+		"x IS NULL OR region IS NOT NULL",
+	}
+
+	for _, condition := range tests {
+		if errs := parseRowFilterCondition(condition, testConditionColumns, allow); len(errs) != 0 {
+			t.Errorf("parseRowFilterCondition(%q) = %v, want no errors", condition, errs)
+		}
+	}
+}
+
+func TestParseRowFilterConditionInvalid(t *testing.T) {
+	allow := testConditionAllowlist(t)
+
+	tests := []struct {
+		condition string
+		want      string
+	}{
+		{"", "condition is empty"},
+		{"region = 'EU'; DROP TABLE t", "multiple statements are not allowed"},
+		{"region = 'EU' -- comment", "comments are not allowed"},
+		{"region = 'EU' /* comment */", "comments are not allowed"},
+		{"region = 'EU", "unterminated string"},
+		{"\"region = 'EU'", "unterminated identifier"},
+		{"region = 1.2.3", `invalid number "1.2.3"`},
+		{"amount > 1e", `invalid number "1e"`},
+		{"amount > 1abc", `invalid number "1abc"`},
+		{"region = 'EU' AND", "unexpected end of condition"},
+		{"region = 'EU')", `unexpected ")"`},
+		{"region IS 'EU'", "expected NULL"},
+		{"region NOT = 'EU'", "expected IN, BETWEEN or LIKE"},
+		{"amount BETWEEN 1 OR 2", "expected AND"},
+		{"name LIKE 'a%' ESCAPE name", "expected an escape string"},
+		{"name LIKE 'a%' ESCAPE '!!'", "escape string must be a single character"},
+		{"region IN (SELECT region FROM t)", "subqueries are not allowed"},
+		{"EXISTS (SELECT 1)", "subqueries are not allowed"},
+		{"missing = 'EU'", `column "missing" does not exist`},
+		{"\"created\" = 1", `column "created" does not exist`},
+		{"t.missing = 'EU'", `column "missing" does not exist`},
+		{"a.b.c.region = 'EU'", "column name has too many qualifiers"},
+		{"t. = 'EU'", "expected a name"},
+		{"VERSION() = 'x' AND region = 'EU'", "function VERSION is not allowed"},
+		{"region ~ 'EU'", `unexpected character '~'`},
+	}
+
+	for _, tt := range tests {
+		errs := parseRowFilterCondition(tt.condition, testConditionColumns, allow)
+		if !conditionErrorsContain(errs, tt.want) {
+			t.Errorf("parseRowFilterCondition(%q) = %v, want %q", tt.condition, errs, tt.want)
+		}
+	}
+}
+
+func TestParseRowFilterConditionConstant(t *testing.T) {
+	allow := testConditionAllowlist(t)
+
+	tests := []struct {
+		condition string
+		want      string
+	}{
+		{"1 = 1", "references no column"},
+		{"region = 'EU' OR 1 = 1", "references no column"},
+		{"region = 'EU' OR TRUE", "references no column"},
+		{"region = 'EU' OR (CURRENT_DATE = CURRENT_DATE)", "references no column"},
+		{"region = region", "compares region with itself"},
+		{"region = 'EU' OR region = region", "compares region with itself"},
+		{"region = 'EU' OR REGION >= region", "compares region with itself"},
+		{"region = 'EU' OR t.region = t.region", "compares t.region with itself"},
+		{"region = 'EU' OR LOWER(name) = lower(name)", "compares LOWER(name) with itself"},
+		{"region = 'EU' OR region IN ('US', region)", "compares region with itself"},
+		{"x IS NULL OR x IS NOT NULL", "tests both x IS NULL and its negation"},
+		{"x IS NOT NULL OR (x IS NULL)", "tests both x IS NULL and its negation"},
+		{"x IS NULL OR NOT x IS NULL", "tests both x IS NULL and its negation"},
+		{"region = 'EU' OR region <> 'EU'", "tests both region = 'EU' and its negation"},
+		{"amount < 1 OR amount >= 1", "tests both amount < 1 and its negation"},
+		{"region IN ('EU') OR region NOT IN ('EU')", "tests both region IN ('EU') and its negation"},
+		{"amount > 0 AND NOT amount > 0", "tests both amount > 0 and its negation"},
+	}
+
+	for _, tt := range tests {
+		errs := parseRowFilterCondition(tt.condition, testConditionColumns, allow)
+		want := "condition " + tt.want + ", it is always true or always false"
+		if !conditionErrorsContain(errs, want) {
+			t.Errorf("parseRowFilterCondition(%q) = %v, want %q", tt.condition, errs, want)
+		}
+	}
+}
+
+func conditionErrorsContain(errs []conditionError, want string) bool {
+	for _, e := range errs {
+		if strings.Contains(e.Msg, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRowFilterAllowlistOf(t *testing.T) {
+	allow, err := rowFilterAllowlistOf(map[string]string{
+		"row_filter_operators": "=,, and ,",
+		"row_filter_functions": "lower,",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOperators := map[string]bool{"=": true, "AND": true}
+	if !reflect.DeepEqual(allow.Operators, wantOperators) {
+		t.Errorf("operators = %v, want %v", allow.Operators, wantOperators)
+	}
+	wantFunctions := map[string]bool{"LOWER": true}
+	if !reflect.DeepEqual(allow.Functions, wantFunctions) {
+		t.Errorf("functions = %v, want %v", allow.Functions, wantFunctions)
+	}
+
+	// Only blank items apply the defaults.
+	allow, err = rowFilterAllowlistOf(map[string]string{
+		"row_filter_operators": ",",
+		"row_filter_functions": " ",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allow.Operators) != len(rowFilterOperators) || len(allow.Functions) != len(defaultRowFilterFunctions) {
+		t.Errorf("allowlist = %v, want the defaults", allow)
+	}
+}
+
+func TestNewRowFilterAllowlistInvalid(t *testing.T) {
+	tests := []struct {
+		operators []string
+		functions []string
+		want      string
+	}{
+		{[]string{"=", "~"}, nil, `unsupported row filter operator "~"`},
+		{[]string{"EXISTS"}, nil, `unsupported row filter operator "EXISTS"`},
+		{nil, []string{"LOWER", "LOWR"}, `unsupported row filter function "LOWR"`},
+		{nil, []string{"pg_sleep"}, `unsupported row filter function "PG_SLEEP"`},
+	}
+
+	for _, tt := range tests {
+		_, err := newRowFilterAllowlist(tt.operators, tt.functions)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("newRowFilterAllowlist(%v, %v) = %v, want %q", tt.operators, tt.functions, err, tt.want)
+		}
+	}
+}
+
+func TestParseRowFilterConditionRestricted(t *testing.T) {
+	allow, err := newRowFilterAllowlist([]string{"=", "AND", "IN"}, []string{"LOWER", "CURRENT_USER"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := []string{
+		"region = 'EU' AND LOWER(name) = 'bob'",
+		"region IN ('EU', 'US') AND name = CURRENT_USER",
+	}
+	for _, condition := range valid {
+		if errs := parseRowFilterCondition(condition, testConditionColumns, allow); len(errs) != 0 {
+			t.Errorf("parseRowFilterCondition(%q) = %v, want no errors", condition, errs)
+		}
+	}
+
+	tests := []struct {
+		condition string
+		want      string
+	}{
+		{"amount > 1", "operator > is not allowed"},
+		{"region = 'EU' OR region = 'US'", "operator OR is not allowed"},
+		{"NOT region = 'EU'", "operator NOT is not allowed"},
+		{"x IS NULL", "operator IS is not allowed"},
+		{"region NOT IN ('EU')", "operator NOT is not allowed"},
+		{"name || 'x' = 'bobx'", "operator || is not allowed"},
+		{"UPPER(name) = 'BOB'", "function UPPER is not allowed"},
+		{"\"Created\" = CURRENT_DATE", "function CURRENT_DATE is not allowed"},
+	}
+	for _, tt := range tests {
+		errs := parseRowFilterCondition(tt.condition, testConditionColumns, allow)
+		if !conditionErrorsContain(errs, tt.want) {
+			t.Errorf("parseRowFilterCondition(%q) = %v, want %q", tt.condition, errs, tt.want)
+		}
+	}
+}
+
+func TestConditionPosition(t *testing.T) {
+	src := "region = 'EU'\n  AND amount > 1\n\nOR 'é' = x"
+
+	tests := []struct {
+		pos    int
+		line   int
+		column int
+	}{
+		{0, 1, 1},
+		{9, 1, 10},
+		{13, 1, 14},
+		{14, 2, 1},
+		{16, 2, 3},
+		{31, 3, 1},
+		{32, 4, 1},
+		// Columns count characters, 'é' takes two bytes.
+		{40, 4, 8},
+	}
+
+	for _, tt := range tests {
+		line, column := conditionPosition(src, tt.pos)
+		if line != tt.line || column != tt.column {
+			t.Errorf("conditionPosition(%d) = %d:%d, want %d:%d", tt.pos, line, column, tt.line, tt.column)
+		}
+	}
+}
+
+func TestValidateRowFilterConditionPositions(t *testing.T) {
+	allow := testConditionAllowlist(t)
+
+	err := validateRowFilterCondition(
+		"region = 'EU'\n  AND missing > 1", "row filter of user \"a\"", testConditionColumns, allow,
+	)
+	want := "invalid SQL condition in row filter of user \"a\":\n" +
+		"  line 2, column 7: column \"missing\" does not exist"
+	if err == nil || err.Error() != want {
+		t.Errorf("validateRowFilterCondition = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,172 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/zipstack/pct-plugin-framework/schema"
+)
+
+// Variable available to SQL templates as {{ .name }}. Values are
+// rendered according to their type, hence a string can not break out
+// of its literal and an identifier can not inject further SQL.
+type sqlVariable struct {
+	Name  string `pctsdk:"name"`
+	Type  string `pctsdk:"type"`
+	Value string `pctsdk:"value"`
+}
+
+const (
+	sqlVariableString     = "string"     // Quoted SQL string literal.
+	sqlVariableIdentifier = "identifier" // Catalog, schema, table or column name, e.g. "sales.orders".
+	sqlVariableNumber     = "number"
+	sqlVariableBoolean    = "boolean"
+)
+
+var (
+	sqlVariableName       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	sqlVariableIdentValue = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)*$`)
+)
+
+func sqlVariablesAttribute() *schema.ListAttribute {
+	return &schema.ListAttribute{
+		Description: "Variables for the SQL templates, referenced as {{ .name }}",
+		Required:    true,
+		Optional:    true,
+		NestedAttribute: &schema.MapAttribute{
+			Description: "Variable",
+			Required:    true,
+			Attributes: map[string]schema.Attribute{
+				"name": &schema.StringAttribute{
+					Description: "Name",
+					Required:    true,
+				},
+				"type": &schema.StringAttribute{
+					Description: "One of string, identifier, number or boolean",
+					Required:    true,
+				},
+				"value": &schema.StringAttribute{
+					Description: "Value",
+					Required:    true,
+				},
+			},
+		},
+	}
+}
+
+// Load the SQL given either inline or as a file and render it with
+// the variables. Relative file paths are resolved against dir, the
+// sql_file_dir of the provider. The framework does not pass the
+// configuration directory, hence without dir they are resolved against
+// the working directory of the plugin process.
+func renderSQL(dir string, inline string, file string, vars []sqlVariable, inlineAttr string, fileAttr string) (string, error) {
+	if (inline == "") == (file == "") {
+		return "", fmt.Errorf("exactly one of %s or %s is required", inlineAttr, fileAttr)
+	}
+
+	name := inlineAttr
+	text := inline
+	if file != "" {
+		b, err := os.ReadFile(sqlFilePath(dir, file))
+		if err != nil {
+			return "", fmt.Errorf("%s: %s", fileAttr, err)
+		}
+		name = file
+		text = string(b)
+	}
+
+	values, err := sqlVariableValues(vars)
+	if err != nil {
+		return "", err
+	}
+	// Plain SQL may contain "{{" itself.
+	if len(values) == 0 {
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid SQL template: %s", err)
+	}
+
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, values)
+	if err != nil {
+		return "", fmt.Errorf("failed to render SQL template: %s", err)
+	}
+
+	return sb.String(), nil
+}
+
+func sqlFilePath(dir string, file string) string {
+	if dir == "" || filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(dir, file)
+}
+
+// Render the variables to SQL according to their types.
+func sqlVariableValues(vars []sqlVariable) (map[string]string, error) {
+	values := map[string]string{}
+
+	for _, v := range vars {
+		if !sqlVariableName.MatchString(v.Name) {
+			return nil, fmt.Errorf("invalid SQL variable name %q", v.Name)
+		}
+		if _, ok := values[v.Name]; ok {
+			return nil, fmt.Errorf("duplicate SQL variable %q", v.Name)
+		}
+
+		switch v.Type {
+		case sqlVariableString:
+			values[v.Name] = "'" + strings.ReplaceAll(v.Value, "'", "''") + "'"
+		case sqlVariableIdentifier:
+			if !sqlVariableIdentValue.MatchString(v.Value) {
+				return nil, fmt.Errorf(
+					"SQL variable %q: %q is not a valid identifier", v.Name, v.Value,
+				)
+			}
+			values[v.Name] = v.Value
+		case sqlVariableNumber:
+			_, err := strconv.ParseFloat(v.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"SQL variable %q: %q is not a number", v.Name, v.Value,
+				)
+			}
+			values[v.Name] = v.Value
+		case sqlVariableBoolean:
+			b, err := strconv.ParseBool(v.Value)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"SQL variable %q: %q is not a boolean", v.Name, v.Value,
+				)
+			}
+			values[v.Name] = strings.ToUpper(strconv.FormatBool(b))
+		default:
+			return nil, fmt.Errorf(
+				"SQL variable %q: unknown type %q, expected one of %s, %s, %s or %s",
+				v.Name, v.Type, sqlVariableString, sqlVariableIdentifier,
+				sqlVariableNumber, sqlVariableBoolean,
+			)
+		}
+	}
+
+	return values, nil
+}
+
+// Keep the configured SQL source while it still renders to the SQL of
+// the server. Otherwise the server SQL is reported inline, so that
+// remote changes as well as edited files show up in the plan.
+func refreshSQLSource(dir string, inline string, file string, vars []sqlVariable, remote string) (string, string) {
+	rendered, err := renderSQL(dir, inline, file, vars, "", "")
+	if err == nil && rendered == remote {
+		return inline, file
+	}
+	return remote, ""
+}
@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSqlVariableValues(t *testing.T) {
+	vars := []sqlVariable{
+		{Name: "region", Type: sqlVariableString, Value: "it's"},
+		{Name: "table", Type: sqlVariableIdentifier, Value: "sales.orders"},
+		{Name: "limit", Type: sqlVariableNumber, Value: "1.5e3"},
+		{Name: "active", Type: sqlVariableBoolean, Value: "1"},
+	}
+	want := map[string]string{
+		"region": "'it''s'",
+		"table":  "sales.orders",
+		"limit":  "1.5e3",
+		"active": "TRUE",
+	}
+
+	got, err := sqlVariableValues(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sqlVariableValues = %v, want %v", got, want)
+	}
+}
+
+func TestSqlVariableValuesInvalid(t *testing.T) {
+	tests := []struct {
+		vars []sqlVariable
+		want string
+	}{
+		{
+			[]sqlVariable{{Name: "1x", Type: sqlVariableString}},
+			`invalid SQL variable name "1x"`,
+		},
+		{
+			[]sqlVariable{{Name: "x", Type: sqlVariableString}, {Name: "x", Type: sqlVariableNumber, Value: "1"}},
+			`duplicate SQL variable "x"`,
+		},
+		{
+			[]sqlVariable{{Name: "t", Type: sqlVariableIdentifier, Value: "orders; DROP TABLE orders"}},
+			`SQL variable "t": "orders; DROP TABLE orders" is not a valid identifier`,
+		},
+		{
+			[]sqlVariable{{Name: "t", Type: sqlVariableIdentifier, Value: "sales..orders"}},
+			`SQL variable "t": "sales..orders" is not a valid identifier`,
+		},
+		{
+			[]sqlVariable{{Name: "n", Type: sqlVariableNumber, Value: "1 OR 1=1"}},
+			`SQL variable "n": "1 OR 1=1" is not a number`,
+		},
+		{
+			[]sqlVariable{{Name: "b", Type: sqlVariableBoolean, Value: "yes"}},
+			`SQL variable "b": "yes" is not a boolean`,
+		},
+		{
+			[]sqlVariable{{Name: "x", Type: "date", Value: "2024-01-01"}},
+			`SQL variable "x": unknown type "date"`,
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := sqlVariableValues(tt.vars)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("sqlVariableValues(%v) = %v, want %q", tt.vars, err, tt.want)
+		}
+	}
+}
+
+func TestRenderSQL(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "select.sql"), []byte("SELECT * FROM {{ .table }}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := []sqlVariable{{Name: "table", Type: sqlVariableIdentifier, Value: "orders"}}
+
+	tests := []struct {
+		name   string
+		dir    string
+		inline string
+		file   string
+		vars   []sqlVariable
+		want   string
+	}{
+		{"inline", "", "SELECT {{ .table }}", "", table, "SELECT orders"},
+		{"inline without variables", "", "SELECT '{{'", "", nil, "SELECT '{{'"},
+		{"file relative to the directory", dir, "", "select.sql", table, "SELECT * FROM orders"},
+		{"absolute file", "/nonexistent", "", filepath.Join(dir, "select.sql"), table, "SELECT * FROM orders"},
+		{
+			"string variable",
+			"", "SELECT * FROM t WHERE region = {{ .region }}", "",
+			[]sqlVariable{{Name: "region", Type: sqlVariableString, Value: "x' OR '1'='1"}},
+			"SELECT * FROM t WHERE region = 'x'' OR ''1''=''1'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderSQL(tt.dir, tt.inline, tt.file, tt.vars, "sql_select", "sql_file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("renderSQL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSQLInvalid(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "select.sql"), []byte("SELECT 1"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := []sqlVariable{{Name: "table", Type: sqlVariableIdentifier, Value: "orders"}}
+
+	tests := []struct {
+		name   string
+		inline string
+		file   string
+		vars   []sqlVariable
+		want   string
+	}{
+		{"neither inline nor file", "", "", nil, "exactly one of sql_select or sql_file is required"},
+		{"both inline and file", "SELECT 1", "select.sql", nil, "exactly one of sql_select or sql_file is required"},
+		{"missing file", "", "missing.sql", nil, "sql_file: open " + filepath.Join(dir, "missing.sql")},
+		{"missing variable", "SELECT * FROM {{ .tabel }}", "", table, `map has no entry for key "tabel"`},
+		{"template error", "SELECT * FROM {{ .table }", "", table, "invalid SQL template"},
+		{"invalid variable", "SELECT {{ .n }}", "", []sqlVariable{{Name: "n", Type: sqlVariableNumber, Value: "x"}}, "is not a number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderSQL(dir, tt.inline, tt.file, tt.vars, "sql_select", "sql_file")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("renderSQL = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshSQLSource(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "select.sql"), []byte("SELECT 1"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inline, file := refreshSQLSource(dir, "", "select.sql", nil, "SELECT 1")
+	if inline != "" || file != "select.sql" {
+		t.Errorf("refreshSQLSource = %q, %q, want the file kept", inline, file)
+	}
+
+	inline, file = refreshSQLSource(dir, "", "select.sql", nil, "SELECT 2")
+	if inline != "SELECT 2" || file != "" {
+		t.Errorf("refreshSQLSource = %q, %q, want the remote SQL inline", inline, file)
+	}
+}
@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zipstack/pct-plugin-framework/schema"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Column of the result of a query as inferred by the server.
+type sqlColumn struct {
+	Name string `pctsdk:"name"`
+	Type string `pctsdk:"type"`
+}
+
+func sqlColumnsAttribute(description string) *schema.ListAttribute {
+	return &schema.ListAttribute{
+		Description: description,
+		Computed:    true,
+		NestedAttribute: &schema.MapAttribute{
+			Description: "Column",
+			Computed:    true,
+			Attributes: map[string]schema.Attribute{
+				"name": &schema.StringAttribute{
+					Description: "Name",
+					Computed:    true,
+				},
+				"type": &schema.StringAttribute{
+					Description: "Type",
+					Computed:    true,
+				},
+			},
+		},
+	}
+}
+
+// Validate the query with the server before any change is made.
+// All errors found are reported with their positions in the query
+// as sent, i.e. after rendering the template.
+func validateSQL(client *api.Client, sql string, source string) ([]sqlColumn, error) {
+	validation, err := client.ValidateSQL(sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate %s: %s", source, err)
+	}
+
+	if !validation.Valid || len(validation.Errors) > 0 {
+		msgs := []string{}
+		for _, e := range validation.Errors {
+			msgs = append(msgs, "  "+e.String())
+		}
+		if len(msgs) == 0 {
+			msgs = append(msgs, "  rejected by the server")
+		}
+
+		return nil, fmt.Errorf("invalid SQL in %s:\n%s", source, strings.Join(msgs, "\n"))
+	}
+
+	columns := []sqlColumn{}
+	for _, c := range validation.Columns {
+		columns = append(columns, sqlColumn{Name: c.Name, Type: c.Type})
+	}
+	return columns, nil
+}
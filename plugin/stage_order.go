@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Order stages so that every stage follows the stages it depends on.
+// Among the stages whose dependencies are satisfied, the one coming
+// first in the given order is picked, hence stages without
+// dependencies keep their configured order.
+//
+// Dependencies are referenced by short name. Unknown references and
+// cycles are reported as errors.
+func orderStages(stages []api.HypertableScheduledStage) ([]api.HypertableScheduledStage, error) {
+	index := map[string]int{}
+	for idx, stage := range stages {
+		index[stage.ShortName] = idx
+	}
+
+	for _, stage := range stages {
+		for _, dep := range stage.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf(
+					"stage %q depends on unknown stage %q", stage.ShortName, dep,
+				)
+			}
+		}
+	}
+
+	ordered := []api.HypertableScheduledStage{}
+	done := make([]bool, len(stages))
+
+	for len(ordered) < len(stages) {
+		picked := -1
+		for idx, stage := range stages {
+			if done[idx] {
+				continue
+			}
+
+			ready := true
+			for _, dep := range stage.DependsOn {
+				if !done[index[dep]] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				picked = idx
+				break
+			}
+		}
+
+		if picked < 0 {
+			return nil, fmt.Errorf(
+				"stage dependencies form a cycle: %s",
+				strings.Join(findStageCycle(stages, index, done), " -> "),
+			)
+		}
+
+		done[picked] = true
+		ordered = append(ordered, stages[picked])
+	}
+
+	return ordered, nil
+}
+
+// Find a cycle among the stages not done yet, each of which depends on
+// at least one other such stage. The first stage is repeated at the end.
+func findStageCycle(stages []api.HypertableScheduledStage, index map[string]int, done []bool) []string {
+	start := 0
+	for done[start] {
+		start++
+	}
+
+	visited := map[int]int{}
+	path := []string{}
+	for idx := start; ; {
+		if pos, ok := visited[idx]; ok {
+			return append(path[pos:], stages[idx].ShortName)
+		}
+		visited[idx] = len(path)
+		path = append(path, stages[idx].ShortName)
+
+		for _, dep := range stages[idx].DependsOn {
+			if !done[index[dep]] {
+				idx = index[dep]
+				break
+			}
+		}
+	}
+}
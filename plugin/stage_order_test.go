@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zipstack/pct-provider-zipstack-cloud/api"
+)
+
+// Stages from "short_name:dependency,..." specs.
+func testStages(specs ...string) []api.HypertableScheduledStage {
+	stages := []api.HypertableScheduledStage{}
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, ":", 2)
+		stage := api.HypertableScheduledStage{ShortName: parts[0]}
+		if len(parts) == 2 {
+			stage.DependsOn = strings.Split(parts[1], ",")
+		}
+		stages = append(stages, stage)
+	}
+	return stages
+}
+
+func TestOrderStages(t *testing.T) {
+	tests := []struct {
+		name   string
+		stages []string
+		want   []string
+	}{
+		{"no dependencies keep their order", []string{"b", "a", "c"}, []string{"b", "a", "c"}},
+		{"linear chain", []string{"c:b", "b:a", "a"}, []string{"a", "b", "c"}},
+		{"diamond", []string{"d:b,c", "c:a", "b:a", "a"}, []string{"a", "c", "b", "d"}},
+		{"independent stages first", []string{"b:a", "x", "a"}, []string{"x", "a", "b"}},
+		{"empty", []string{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := orderStages(testStages(tt.stages...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, stage := range ordered {
+				got = append(got, stage.ShortName)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderStages(%v) = %v, want %v", tt.stages, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderStagesInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		stages []string
+		want   string
+	}{
+		{
+			"missing dependency",
+			[]string{"a", "b:a,z"},
+			`stage "b" depends on unknown stage "z"`,
+		},
+		{
+			"self dependency",
+			[]string{"a:a"},
+			"stage dependencies form a cycle: a -> a",
+		},
+		{
+			"multi-node cycle",
+			[]string{"x", "a:c", "b:a", "c:b"},
+			"stage dependencies form a cycle: a -> c -> b -> a",
+		},
+		{
+			"stage depending on a cycle",
+			[]string{"e:a", "a:b", "b:a"},
+			"stage dependencies form a cycle: a -> b -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := orderStages(testStages(tt.stages...))
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("orderStages(%v) = %v, want %q", tt.stages, err, tt.want)
+			}
+		})
+	}
+}